@@ -0,0 +1,187 @@
+package go_pascal
+
+// dynamicArray is the value of a dynamic array. NIL, the initial value, is
+// the empty array. Assigning a dynamic array shares its elements, but
+// SetLength always gives the variable new ones, so changes through other
+// variables are no longer seen, as in FPC.
+type dynamicArray struct {
+	elements []interface{}
+}
+
+// arrayElements returns the elements of a static, dynamic or open array
+// value. A function result that has not been assigned yet is empty.
+func arrayElements(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		return v
+	case *dynamicArray:
+		if v != nil {
+			return v.elements
+		}
+	}
+	return nil
+}
+
+// newDynamicArray returns a dynamic array holding elements, which is NIL if
+// there are none.
+func newDynamicArray(elements []interface{}) *dynamicArray {
+	if len(elements) == 0 {
+		return nil
+	}
+	return &dynamicArray{elements: elements}
+}
+
+// resize returns a copy of the dynamic array v of type t with the given
+// lengths, the first one for the array itself and the others for the arrays
+// it holds. Elements beyond the old length get their initial value.
+func resize(t *dynamicArrayType, v interface{}, lengths []int) interface{} {
+	old := arrayElements(v)
+	elements := make([]interface{}, lengths[0])
+	for index := range elements {
+		if index < len(old) {
+			elements[index] = copyValue(old[index])
+		} else {
+			elements[index] = zeroValue(t.element)
+		}
+		if element, ok := t.element.(*dynamicArrayType); ok && len(lengths) > 1 {
+			elements[index] = resize(element, elements[index], lengths[1:])
+		}
+	}
+	return newDynamicArray(elements)
+}
+
+func checkSetLength(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if len(call.args) < 2 {
+		return nil, newErrSemantic(call.name, "%v expects at least 2 arguments; got %d", call.name.value, len(call.args))
+	}
+	valid := isDynamicArray(call.argTypes[0]) && isVariable(call.args[0])
+	if err := checkArg(call, 0, valid, "a dynamic array variable"); err != nil {
+		return nil, err
+	}
+	element := call.argTypes[0]
+	for index := 1; index < len(call.args); index++ {
+		array, ok := element.(*dynamicArrayType)
+		if !ok {
+			return nil, newErrSemantic(call.name, "too many lengths for %v", call.argTypes[0])
+		}
+		element = array.element
+		if err := checkArg(call, index, isInteger(call.argTypes[index]), "an integer"); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// runSetLength gives a dynamic array variable new elements with the given
+// lengths, keeping the old ones that fit.
+func runSetLength(i *interpreter, call *builtinCall) (interface{}, error) {
+	args, err := evalArgs(i, call)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := i.reference(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	lengths := make([]int, len(args)-1)
+	for index, arg := range args[1:] {
+		length, ok := arg.(int)
+		if !ok || length < 0 || length > maxArrayLength {
+			return nil, newErrRuntime(errCodeRangeCheck, "invalid length %v for %v", arg, call.argTypes[0])
+		}
+		lengths[index] = length
+	}
+	ref.set(resize(call.argTypes[0].(*dynamicArrayType), ref.get(), lengths))
+	return nil, nil
+}
+
+func checkHighLow(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkArgCount(call, 1); err != nil {
+		return nil, err
+	}
+	switch t := call.argTypes[0].(type) {
+	case *arrayType:
+		return t.index, nil
+	case *dynamicArrayType:
+		return typeInt64, nil
+	}
+	_, ok := ordinalBounds(call.argTypes[0])
+	if err := checkArg(call, 0, ok, "an array or a value of a bounded ordinal type"); err != nil {
+		return nil, err
+	}
+	return call.argTypes[0], nil
+}
+
+// runHighLow returns the last or the first index of an array, or the
+// largest or the smallest value of an ordinal type. Dynamic and open arrays
+// are indexed from 0, so High is -1 if they are empty.
+func runHighLow(i *interpreter, call *builtinCall) (interface{}, error) {
+	high := call.name.value == "high"
+	if _, ok := call.argTypes[0].(*dynamicArrayType); ok {
+		value, err := i.visit(call.args[0])
+		if err != nil {
+			return nil, err
+		}
+		if high {
+			return len(arrayElements(value)) - 1, nil
+		}
+		return 0, nil
+	}
+	typ := call.argTypes[0]
+	if array, ok := typ.(*arrayType); ok {
+		typ = array.index
+	}
+	bounds, _ := ordinalBounds(typ)
+	if high {
+		return ordinalToValue(typ, bounds.high), nil
+	}
+	return ordinalToValue(typ, bounds.low), nil
+}
+
+func checkCopyArray(call *builtinCall, array *dynamicArrayType) (dataType, error) {
+	if len(call.args) > 3 {
+		return nil, newErrSemantic(call.name, "%v expects at most 3 arguments; got %d", call.name.value, len(call.args))
+	}
+	for index := 1; index < len(call.args); index++ {
+		if err := checkArg(call, index, isInteger(call.argTypes[index]), "an integer"); err != nil {
+			return nil, err
+		}
+	}
+	if array.open {
+		return newDynamicArrayType(array.element), nil
+	}
+	return array, nil
+}
+
+// runCopyArray returns a new dynamic array holding count elements of a
+// dynamic or open array starting at index, or the elements up to the end if
+// count is not given, or all of them if index is not given either. Like Copy
+// of strings, it returns as many elements as there are.
+func runCopyArray(i *interpreter, call *builtinCall) (interface{}, error) {
+	args, err := evalArgs(i, call)
+	if err != nil {
+		return nil, err
+	}
+	elements := arrayElements(args[0])
+	index, count := 0, len(elements)
+	if len(args) > 1 {
+		index = ordinalValue(args[1])
+	}
+	if len(args) > 2 {
+		count = ordinalValue(args[2])
+	}
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(elements) || count <= 0 {
+		return (*dynamicArray)(nil), nil
+	}
+	if count > len(elements)-index {
+		count = len(elements) - index
+	}
+	copies := make([]interface{}, count)
+	for offset := range copies {
+		copies[offset] = copyValue(elements[index+offset])
+	}
+	return newDynamicArray(copies), nil
+}
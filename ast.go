@@ -34,6 +34,41 @@ func newDeclNode(children []node) node {
 	return &declNode{children: children}
 }
 
+// routineDeclNode declares a procedure or a function. returnType is nil for
+// procedures, and for functions whose heading was given in a FORWARD
+// declaration. block is nil for FORWARD declarations.
+type routineDeclNode struct {
+	name       *token
+	isFunction bool
+	params     []node
+	returnType node
+	block      node
+}
+
+func newRoutineDeclNode(name *token, isFunction bool, params []node, returnType, block node) node {
+	return &routineDeclNode{
+		name:       name,
+		isFunction: isFunction,
+		params:     params,
+		returnType: returnType,
+		block:      block,
+	}
+}
+
+// paramNode is a formal parameter. byRef is true for VAR parameters.
+type paramNode struct {
+	varNode, typeNode node
+	byRef             bool
+}
+
+func newParamNode(varNode, typeNode node, byRef bool) node {
+	return &paramNode{
+		varNode:  varNode,
+		typeNode: typeNode,
+		byRef:    byRef,
+	}
+}
+
 type compoundNode struct {
 	children []node
 }
@@ -42,25 +77,119 @@ func newCompoundNode(children []node) node {
 	return &compoundNode{children: children}
 }
 
+// varDeclNode declares a variable. value is the initial value of the
+// variable, or nil if it has none.
 type varDeclNode struct {
 	varNode, typeNode node
+	value             node
+	// initialValue is value folded by the semantic analyzer.
+	initialValue interface{}
 }
 
-func newVarDeclNode(varNode, typeNode node) node {
+func newVarDeclNode(varNode, typeNode, value node) node {
 	return &varDeclNode{
 		varNode:  varNode,
 		typeNode: typeNode,
+		value:    value,
+	}
+}
+
+// constDeclNode declares a constant. typeNode is nil for untyped constants.
+type constDeclNode struct {
+	name     *token
+	typeNode node
+	value    node
+}
+
+func newConstDeclNode(name *token, typeNode, value node) node {
+	return &constDeclNode{
+		name:     name,
+		typeNode: typeNode,
+		value:    value,
 	}
 }
 
 type varNode struct {
 	t *token
+	// symbol is resolved by the semantic analyzer. If the identifier turns
+	// out to be a constant, a function without parameters or a field of a
+	// WITH statement's record, constant, call or field is set instead.
+	// routine is set if the identifier names a routine that is taken as a
+	// value of a procedural type rather than called.
+	symbol   *varSymbol
+	constant *constSymbol
+	call     node
+	field    node
+	routine  *routineSymbol
 }
 
 func newVarNode(t *token) node {
 	return &varNode{t: t}
 }
 
+// indexNode is an indexed variable such as a[i]. a[i, j] is parsed as
+// a[i][j].
+type indexNode struct {
+	// t is the first token of the index expression.
+	t     *token
+	array node
+	index node
+	// typ is the type of array, an array or a string type. It is resolved by
+	// the semantic analyzer.
+	typ dataType
+}
+
+func newIndexNode(t *token, array, index node) node {
+	return &indexNode{
+		t:     t,
+		array: array,
+		index: index,
+	}
+}
+
+// fieldNode selects a field of a record, such as r.field.
+type fieldNode struct {
+	t      *token
+	record node
+	// symbol is resolved by the semantic analyzer.
+	symbol *fieldSymbol
+}
+
+func newFieldNode(t *token, record node) node {
+	return &fieldNode{
+		t:      t,
+		record: record,
+	}
+}
+
+// derefNode is the variable a pointer points to, such as p^.
+type derefNode struct {
+	t       *token
+	pointer node
+}
+
+func newDerefNode(t *token, pointer node) node {
+	return &derefNode{
+		t:       t,
+		pointer: pointer,
+	}
+}
+
+// addressNode takes a routine as a value of a procedural type, such as
+// @Compare.
+type addressNode struct {
+	t       *token
+	routine node
+}
+
+func newAddressNode(t *token, routine node) node {
+	return &addressNode{
+		t:       t,
+		routine: routine,
+	}
+}
+
+// typeNode is a type named by a keyword such as INTEGER or by an identifier.
 type typeNode struct {
 	t *token
 }
@@ -69,46 +198,456 @@ func newTypeNode(t *token) node {
 	return &typeNode{t: t}
 }
 
+// arrayTypeNode is an array type. An array with several index types is
+// parsed as an array of arrays. indexType is nil for ARRAY OF T.
+type arrayTypeNode struct {
+	t           *token
+	indexType   node
+	elementType node
+	// typ is the type created by the semantic analyzer. It is shared by all
+	// variables declared with the array type.
+	typ dataType
+}
+
+func newArrayTypeNode(t *token, indexType, elementType node) node {
+	return &arrayTypeNode{
+		t:           t,
+		indexType:   indexType,
+		elementType: elementType,
+	}
+}
+
+type recordTypeNode struct {
+	t      *token
+	fields node
+	// typ is the type created by the semantic analyzer. It is shared by all
+	// variables declared with the record type.
+	typ dataType
+}
+
+func newRecordTypeNode(t *token, fields node) node {
+	return &recordTypeNode{
+		t:      t,
+		fields: fields,
+	}
+}
+
+// fieldListNode holds the fields of a record or of a record variant. variant
+// is nil if there is no variant part.
+type fieldListNode struct {
+	fields  []node
+	variant node
+}
+
+func newFieldListNode(fields []node, variant node) node {
+	return &fieldListNode{
+		fields:  fields,
+		variant: variant,
+	}
+}
+
+type fieldDeclNode struct {
+	names    []*token
+	typeNode node
+}
+
+func newFieldDeclNode(names []*token, typeNode node) node {
+	return &fieldDeclNode{
+		names:    names,
+		typeNode: typeNode,
+	}
+}
+
+// variantPartNode is the variant part of a record. tag is nil if the variant
+// part has no tag field.
+type variantPartNode struct {
+	t        *token
+	tag      *token
+	tagType  node
+	variants []node
+}
+
+func newVariantPartNode(t, tag *token, tagType node, variants []node) node {
+	return &variantPartNode{
+		t:        t,
+		tag:      tag,
+		tagType:  tagType,
+		variants: variants,
+	}
+}
+
+type variantNode struct {
+	labels []node
+	fields node
+}
+
+func newVariantNode(labels []node, fields node) node {
+	return &variantNode{
+		labels: labels,
+		fields: fields,
+	}
+}
+
+type setTypeNode struct {
+	t        *token
+	baseType node
+}
+
+func newSetTypeNode(t *token, baseType node) node {
+	return &setTypeNode{
+		t:        t,
+		baseType: baseType,
+	}
+}
+
+// fileTypeNode is a typed file type such as FILE OF INTEGER.
+type fileTypeNode struct {
+	t           *token
+	elementType node
+}
+
+func newFileTypeNode(t *token, elementType node) node {
+	return &fileTypeNode{
+		t:           t,
+		elementType: elementType,
+	}
+}
+
+// pointerTypeNode is a pointer type such as ^node. The target type may be
+// declared later in the same TYPE section.
+type pointerTypeNode struct {
+	t      *token
+	target node
+}
+
+func newPointerTypeNode(t *token, target node) node {
+	return &pointerTypeNode{
+		t:      t,
+		target: target,
+	}
+}
+
+// procedureTypeNode is a procedural type such as
+// FUNCTION(a, b: INTEGER): INTEGER. returnType is nil for procedures.
+type procedureTypeNode struct {
+	t          *token
+	params     []node
+	returnType node
+}
+
+func newProcedureTypeNode(t *token, params []node, returnType node) node {
+	return &procedureTypeNode{
+		t:          t,
+		params:     params,
+		returnType: returnType,
+	}
+}
+
+// stringTypeNode is a string type with a maximum length, such as
+// STRING[10].
+type stringTypeNode struct {
+	t    *token
+	size node
+}
+
+func newStringTypeNode(t *token, size node) node {
+	return &stringTypeNode{
+		t:    t,
+		size: size,
+	}
+}
+
+type typeDeclNode struct {
+	name     *token
+	typeNode node
+}
+
+func newTypeDeclNode(name *token, typeNode node) node {
+	return &typeDeclNode{
+		name:     name,
+		typeNode: typeNode,
+	}
+}
+
+type enumTypeNode struct {
+	values []*token
+	// typ is the type created by the semantic analyzer. It is shared by all
+	// variables declared with the enumeration.
+	typ dataType
+}
+
+func newEnumTypeNode(values []*token) node {
+	return &enumTypeNode{values: values}
+}
+
+type subrangeTypeNode struct {
+	t         *token
+	low, high node
+}
+
+func newSubrangeTypeNode(t *token, low, high node) node {
+	return &subrangeTypeNode{
+		t:    t,
+		low:  low,
+		high: high,
+	}
+}
+
 type assignNode struct {
 	t           *token
 	left, right node
+	switches    switches
+	// typ is the type of left, resolved by the semantic analyzer.
+	typ dataType
 }
 
-func newAssignNode(t *token, left, right node) node {
+func newAssignNode(t *token, left, right node, switches switches) node {
 	return &assignNode{
-		t:     t,
-		left:  left,
-		right: right,
+		t:        t,
+		left:     left,
+		right:    right,
+		switches: switches,
+	}
+}
+
+// procedureCallNode calls a procedure. The call is resolved by the semantic
+// analyzer to a declared routine, a built-in one or a variable of a
+// procedural type.
+type procedureCallNode struct {
+	name     *token
+	args     []node
+	switches switches
+
+	symbol   *routineSymbol
+	builtin  *builtinSymbol
+	argTypes []dataType
+	// variable is set instead of symbol if the call goes through a
+	// variable of a procedural type. The parser sets it for a component
+	// such as r.handler, and the semantic analyzer for a name.
+	variable node
+}
+
+func newProcedureCallNode(name *token, args []node, switches switches) node {
+	return &procedureCallNode{
+		name:     name,
+		args:     args,
+		switches: switches,
+	}
+}
+
+// functionCallNode calls a function. The call is resolved by the semantic
+// analyzer to a declared routine, a built-in one or a variable of a
+// procedural type.
+type functionCallNode struct {
+	name     *token
+	args     []node
+	switches switches
+
+	symbol   *routineSymbol
+	builtin  *builtinSymbol
+	argTypes []dataType
+	// variable is set instead of symbol if the call goes through a
+	// variable of a procedural type. The parser sets it for a component
+	// such as r.handler, and the semantic analyzer for a name.
+	variable node
+}
+
+func newFunctionCallNode(name *token, args []node, switches switches) node {
+	return &functionCallNode{
+		name:     name,
+		args:     args,
+		switches: switches,
+	}
+}
+
+// formatNode is an argument of Write or WriteLn with a field width, such as
+// x:8:2. decimals is nil if the number of decimals is not given.
+type formatNode struct {
+	t            *token
+	value, width node
+	decimals     node
+}
+
+func newFormatNode(t *token, value, width, decimals node) node {
+	return &formatNode{
+		t:        t,
+		value:    value,
+		width:    width,
+		decimals: decimals,
+	}
+}
+
+type ifNode struct {
+	condition, thenNode, elseNode node
+}
+
+// newIfNode creates an if statement node. elseNode is nil if the statement
+// has no ELSE branch.
+func newIfNode(condition, thenNode, elseNode node) node {
+	return &ifNode{
+		condition: condition,
+		thenNode:  thenNode,
+		elseNode:  elseNode,
+	}
+}
+
+type withNode struct {
+	records []node
+	body    node
+	// symbols hold the records during the execution of the body. They are
+	// created by the semantic analyzer.
+	symbols []*varSymbol
+}
+
+func newWithNode(records []node, body node) node {
+	return &withNode{
+		records: records,
+		body:    body,
+	}
+}
+
+type whileNode struct {
+	condition, body node
+}
+
+func newWhileNode(condition, body node) node {
+	return &whileNode{
+		condition: condition,
+		body:      body,
+	}
+}
+
+type repeatNode struct {
+	children  []node
+	condition node
+}
+
+func newRepeatNode(children []node, condition node) node {
+	return &repeatNode{
+		children:  children,
+		condition: condition,
+	}
+}
+
+type forNode struct {
+	variable   node
+	start, end node
+	downto     bool
+	body       node
+	switches   switches
+}
+
+func newForNode(variable, start, end node, downto bool, body node, switches switches) node {
+	return &forNode{
+		variable: variable,
+		start:    start,
+		end:      end,
+		downto:   downto,
+		body:     body,
+		switches: switches,
+	}
+}
+
+type caseNode struct {
+	t        *token
+	selector node
+	branches []node
+	// elseNode is nil if the statement has no ELSE branch.
+	elseNode node
+}
+
+func newCaseNode(t *token, selector node, branches []node, elseNode node) node {
+	return &caseNode{
+		t:        t,
+		selector: selector,
+		branches: branches,
+		elseNode: elseNode,
+	}
+}
+
+type caseBranchNode struct {
+	labels []node
+	body   node
+	// ranges holds the ordinal values of the labels. It is filled in by the
+	// semantic analyzer.
+	ranges []ordinalRange
+}
+
+func newCaseBranchNode(labels []node, body node) node {
+	return &caseBranchNode{
+		labels: labels,
+		body:   body,
+	}
+}
+
+// rangeNode is a range of ordinal values such as 3..5.
+type rangeNode struct {
+	t         *token
+	low, high node
+}
+
+func newRangeNode(t *token, low, high node) node {
+	return &rangeNode{
+		t:    t,
+		low:  low,
+		high: high,
 	}
 }
 
 type binaryNode struct {
 	t           *token
 	left, right node
+	switches    switches
+	// typ is the type of the result, resolved by the semantic analyzer.
+	// Integer results wrap around to it.
+	typ dataType
 }
 
-func newBinaryNode(t *token, left, right node) node {
+func newBinaryNode(t *token, left, right node, switches switches) node {
 	return &binaryNode{
-		t:     t,
-		left:  left,
-		right: right,
+		t:        t,
+		left:     left,
+		right:    right,
+		switches: switches,
+	}
+}
+
+// setNode is a set constructor such as [1, 3..5]. Its elements are
+// expressions or ranges.
+type setNode struct {
+	t        *token
+	elements []node
+}
+
+func newSetNode(t *token, elements []node) node {
+	return &setNode{
+		t:        t,
+		elements: elements,
 	}
 }
 
 type unaryNode struct {
-	t     *token
-	child node
+	t        *token
+	child    node
+	switches switches
+	// typ is the type of the result, resolved by the semantic analyzer.
+	typ dataType
 }
 
-func newUnaryNode(t *token, child node) node {
+func newUnaryNode(t *token, child node, switches switches) node {
 	return &unaryNode{
-		t:     t,
-		child: child,
+		t:        t,
+		child:    child,
+		switches: switches,
 	}
 }
 
 type valueNode struct {
 	t *token
+	// exact is set for a real literal with more digits than a real holds
+	// that is assigned to a fixed-point type. Its value is the exact
+	// decimal then, rather than the nearest real.
+	exact bool
 }
 
 func newValueNode(t *token) node {
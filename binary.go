@@ -0,0 +1,225 @@
+package go_pascal
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The components of typed files are stored in a fixed binary layout, so
+// that files written by one run can be read by another, or by other tools.
+// Each type has a fixed size and there is no padding:
+//
+//   - Integer types: as many bytes as the type has bits divided by 8, two's
+//     complement, little endian. SHORTINT and BYTE take 1 byte, SMALLINT
+//     and WORD 2 bytes, LONGINT and CARDINAL 4 bytes and INT64 8 bytes.
+//     INTEGER takes 2 or 4 bytes depending on the dialect.
+//   - REAL, DOUBLE and EXTENDED: 8 bytes, IEEE 754 binary64, little endian.
+//     EXTENDED has the precision of DOUBLE.
+//   - SINGLE: 4 bytes, IEEE 754 binary32, little endian.
+//   - COMP: 8 bytes, two's complement, little endian. NaN and values out of
+//     the range of 64 bits are stored as -2^63, as the FPU does.
+//   - COMPLEX: 16 bytes, the real and the imaginary part, each like REAL.
+//   - CURRENCY: 8 bytes, the value scaled by 10000 as a two's complement
+//     integer, little endian, as in Delphi.
+//   - BOOLEAN and CHAR: 1 byte. FALSE is 0 and TRUE is 1.
+//   - Enumerations: 4 bytes holding the ordinal value, little endian.
+//   - Subranges: the layout of their base type.
+//   - STRING[n]: n+1 bytes, a length byte followed by n bytes of which the
+//     unused ones are 0, as in Turbo Pascal. STRING takes 256 bytes.
+//   - Arrays: the elements in index order.
+//   - Records: the fields in declaration order. The fields of different
+//     variants follow each other.
+//   - Sets: 32 bytes. The value k is bit k%8 of byte k/8.
+//
+// Pointers, files, procedural values, dynamic arrays, DECIMAL and INTEGER
+// without bounds cannot be stored in typed files.
+
+// isStorable returns true if values of type t can be stored in typed files.
+func isStorable(t dataType) bool {
+	switch t := t.(type) {
+	case *pointerType, *fileType, *procedureType, *dynamicArrayType:
+		return false
+	case *integerType:
+		return !t.unbounded()
+	case *decimalType:
+		return t.currency
+	case *subrangeType:
+		return isStorable(t.base)
+	case *arrayType:
+		return isStorable(t.element)
+	case *recordType:
+		for _, field := range t.fields {
+			if !isStorable(field.typ) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// storedSize returns the number of bytes a value of a storable type takes in
+// a typed file.
+func storedSize(t dataType) int {
+	switch t := baseType(t).(type) {
+	case *integerType:
+		return int(t.bits / 8)
+	case *realType:
+		if t.single {
+			return 4
+		}
+		return 8
+	case *decimalType:
+		return 8
+	case *complexType:
+		return 16
+	case *booleanType, *charType:
+		return 1
+	case *enumType:
+		return 4
+	case *stringType:
+		return t.size + 1
+	case *arrayType:
+		return t.length() * storedSize(t.element)
+	case *recordType:
+		size := 0
+		for _, field := range t.fields {
+			size += storedSize(field.typ)
+		}
+		return size
+	case *setType:
+		return (maxSetOrdinal + 1) / 8
+	}
+	panic("unstorable type " + t.String())
+}
+
+// encodeValue appends the stored form of a value of type t to b.
+func encodeValue(b []byte, t dataType, v interface{}) []byte {
+	switch t := baseType(t).(type) {
+	case *integerType:
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], uint64(v.(int)))
+		return append(b, buf[:t.bits/8]...)
+	case *realType:
+		switch {
+		case t.single:
+			return appendUint32(b, math.Float32bits(float32(v.(float64))))
+		case t.integral:
+			return appendUint64(b, uint64(compValue(v.(float64))))
+		}
+		return appendUint64(b, math.Float64bits(v.(float64)))
+	case *decimalType:
+		return appendUint64(b, uint64(v.(currency)))
+	case *complexType:
+		z := v.(complex128)
+		return appendUint64(appendUint64(b, math.Float64bits(real(z))), math.Float64bits(imag(z)))
+	case *booleanType:
+		if v.(bool) {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case *charType:
+		return append(b, v.(byte))
+	case *enumType:
+		return appendUint32(b, uint32(v.(int)))
+	case *stringType:
+		s := v.(string)
+		b = append(b, byte(len(s)))
+		b = append(b, s...)
+		return append(b, make([]byte, t.size-len(s))...)
+	case *arrayType:
+		for _, element := range v.([]interface{}) {
+			b = encodeValue(b, t.element, element)
+		}
+		return b
+	case *recordType:
+		for index, field := range v.([]interface{}) {
+			b = encodeValue(b, t.fields[index].typ, field)
+		}
+		return b
+	case *setType:
+		for _, word := range v.(set) {
+			b = appendUint64(b, word)
+		}
+		return b
+	}
+	panic("unstorable type " + t.String())
+}
+
+// compValue converts a COMP value to the integer it is stored as.
+func compValue(x float64) int64 {
+	// float64(math.MaxInt64) rounds up to 2^63, which is out of range.
+	if math.IsNaN(x) || x < math.MinInt64 || x >= math.MaxInt64 {
+		return math.MinInt64
+	}
+	return int64(x)
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// decodeValue decodes a value of type t from the start of b, which holds at
+// least storedSize(t) bytes. It returns the value and the rest of b.
+func decodeValue(b []byte, t dataType) (interface{}, []byte) {
+	switch t := baseType(t).(type) {
+	case *integerType:
+		var buf [8]byte
+		size := copy(buf[:t.bits/8], b)
+		return t.wrap(int(binary.LittleEndian.Uint64(buf[:]))), b[size:]
+	case *realType:
+		switch {
+		case t.single:
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), b[4:]
+		case t.integral:
+			if n := int64(binary.LittleEndian.Uint64(b)); n != math.MinInt64 {
+				return float64(n), b[8:]
+			}
+			return math.NaN(), b[8:]
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), b[8:]
+	case *decimalType:
+		return currency(binary.LittleEndian.Uint64(b)), b[8:]
+	case *complexType:
+		x, y := binary.LittleEndian.Uint64(b), binary.LittleEndian.Uint64(b[8:])
+		return complex(math.Float64frombits(x), math.Float64frombits(y)), b[16:]
+	case *booleanType:
+		return b[0] != 0, b[1:]
+	case *charType:
+		return b[0], b[1:]
+	case *enumType:
+		return int(int32(binary.LittleEndian.Uint32(b))), b[4:]
+	case *stringType:
+		length := int(b[0])
+		if length > t.size {
+			length = t.size
+		}
+		return string(b[1 : 1+length]), b[1+t.size:]
+	case *arrayType:
+		elements := make([]interface{}, t.length())
+		for index := range elements {
+			elements[index], b = decodeValue(b, t.element)
+		}
+		return elements, b
+	case *recordType:
+		fields := make([]interface{}, len(t.fields))
+		for index, field := range t.fields {
+			fields[index], b = decodeValue(b, field.typ)
+		}
+		return fields, b
+	case *setType:
+		var s set
+		for index := range s {
+			s[index], b = binary.LittleEndian.Uint64(b), b[8:]
+		}
+		return s, b
+	}
+	panic("unstorable type " + t.String())
+}
@@ -0,0 +1,937 @@
+package go_pascal
+
+import "strings"
+
+// builtinSymbol is a standard procedure or function such as Ord. Built-in
+// routines are declared in a scope enclosing the program, so programs can
+// redeclare them.
+type builtinSymbol struct {
+	name string
+	// pure is true for functions that can be evaluated at compile time if
+	// their arguments are constant.
+	pure bool
+	// formatted is true for routines whose arguments may have a field width,
+	// such as WriteLn.
+	formatted bool
+	// check checks the arguments of a call, whose types have already been
+	// computed, and returns the result type, or nil for procedures.
+	check func(s *semanticAnalyzer, call *builtinCall) (dataType, error)
+	run   builtinRun
+}
+
+// builtinRun runs a call of a built-in routine and returns the result of
+// functions.
+type builtinRun func(i *interpreter, call *builtinCall) (interface{}, error)
+
+func (s *builtinSymbol) symbolName() string {
+	return s.name
+}
+
+// builtinCall is a call of a built-in routine.
+type builtinCall struct {
+	name     *token
+	args     []node
+	argTypes []dataType
+	switches switches
+}
+
+var builtins = []*builtinSymbol{
+	{name: "ord", pure: true, check: checkOrd, run: runOrd},
+	{name: "succ", pure: true, check: checkSuccPred, run: runSuccPred},
+	{name: "pred", pure: true, check: checkSuccPred, run: runSuccPred},
+	{name: "new", check: checkNewDispose, run: runNew},
+	{name: "dispose", check: checkNewDispose, run: runDispose},
+	{name: "length", pure: true, check: checkLength, run: runLength},
+	{name: "copy", pure: true, check: checkCopy, run: runCopy},
+	{name: "setlength", check: checkSetLength, run: runSetLength},
+	{name: "high", pure: true, check: checkHighLow, run: runHighLow},
+	{name: "low", pure: true, check: checkHighLow, run: runHighLow},
+	{name: "pos", pure: true, check: checkPos, run: runPos},
+	{name: "concat", pure: true, check: checkConcat, run: runConcat},
+	{name: "upcase", pure: true, check: checkUpCase, run: runUpCase},
+	{name: "chr", pure: true, check: checkChr, run: runChr},
+	{name: "abs", pure: true, check: checkAbsSqr, run: runAbsSqr},
+	{name: "sqr", pure: true, check: checkAbsSqr, run: runAbsSqr},
+	{name: "sqrt", pure: true, check: checkRealFunction, run: runRealFunction},
+	{name: "sin", pure: true, check: checkRealFunction, run: runRealFunction},
+	{name: "cos", pure: true, check: checkRealFunction, run: runRealFunction},
+	{name: "tan", pure: true, check: checkRealFunction, run: runRealFunction},
+	{name: "arctan", pure: true, check: checkRealFunction, run: runRealFunction},
+	{name: "exp", pure: true, check: checkRealFunction, run: runRealFunction},
+	{name: "ln", pure: true, check: checkRealFunction, run: runRealFunction},
+	{name: "trunc", pure: true, check: checkToInteger, run: runToInteger},
+	{name: "round", pure: true, check: checkToInteger, run: runToInteger},
+	{name: "floor", pure: true, check: checkToInteger, run: runToInteger},
+	{name: "ceil", pure: true, check: checkToInteger, run: runToInteger},
+	{name: "odd", pure: true, check: checkOdd, run: runOdd},
+	{name: "power", pure: true, check: checkPower, run: runPower},
+	{name: "cmplx", pure: true, check: checkCmplxPolar, run: runCmplxPolar},
+	{name: "polar", pure: true, check: checkCmplxPolar, run: runCmplxPolar},
+	{name: "re", pure: true, check: checkComplexPart, run: runComplexPart},
+	{name: "im", pure: true, check: checkComplexPart, run: runComplexPart},
+	{name: "arg", pure: true, check: checkComplexPart, run: runComplexPart},
+	{name: "min", pure: true, check: checkMinMax, run: runMinMax},
+	{name: "max", pure: true, check: checkMinMax, run: runMinMax},
+	{name: "insert", check: checkInsert, run: runInsert},
+	{name: "delete", check: checkDelete, run: runDelete},
+	{name: "write", formatted: true, check: checkWrite, run: ioRoutine(runWrite, nil)},
+	{name: "writeln", formatted: true, check: checkWrite, run: ioRoutine(runWrite, nil)},
+	{name: "read", check: checkRead, run: ioRoutine(runRead, nil)},
+	{name: "readln", check: checkRead, run: ioRoutine(runRead, nil)},
+	{name: "assign", check: checkAssign, run: runAssign},
+	{name: "reset", check: checkFileProc, run: ioRoutine(runFileProc, nil)},
+	{name: "rewrite", check: checkFileProc, run: ioRoutine(runFileProc, nil)},
+	{name: "append", check: checkFileProc, run: ioRoutine(runFileProc, nil)},
+	{name: "close", check: checkFileProc, run: ioRoutine(runFileProc, nil)},
+	{name: "eof", check: checkEof, run: ioRoutine(runEof, true)},
+	{name: "eoln", check: checkEof, run: ioRoutine(runEof, true)},
+	{name: "ioresult", check: checkIOResult, run: runIOResult},
+	{name: "seek", check: checkSeek, run: ioRoutine(runSeek, nil)},
+	{name: "filepos", check: checkTypedFileRoutine, run: ioRoutine(runTypedFileRoutine, 0)},
+	{name: "filesize", check: checkTypedFileRoutine, run: ioRoutine(runTypedFileRoutine, 0)},
+	{name: "truncate", check: checkTypedFileRoutine, run: ioRoutine(runTypedFileRoutine, nil)},
+}
+
+// newBuiltinScope creates the outermost scope, which holds the built-in
+// routines and the predeclared types that are not keywords.
+func newBuiltinScope() *scopedSymbolTable {
+	scope := newScopedSymbolTable("builtins", 0, nil)
+	for _, builtin := range builtins {
+		scope.insert(builtin)
+	}
+	for _, t := range []*integerType{typeShortInt, typeByte, typeSmallInt, typeWord, typeLongInt, typeCardinal, typeInt64} {
+		scope.insert(newTypeSymbol(strings.ToLower(t.name), t))
+	}
+	for name, t := range map[string]*realType{"single": typeSingle, "double": typeReal, "extended": typeReal, "comp": typeComp} {
+		scope.insert(newTypeSymbol(name, t))
+	}
+	for _, t := range []*decimalType{typeCurrency, typeDecimal} {
+		scope.insert(newTypeSymbol(strings.ToLower(t.name), t))
+	}
+	scope.insert(newTypeSymbol("complex", typeComplex))
+	scope.insert(newTypeSymbol("text", typeText))
+	return scope
+}
+
+func checkArgCount(call *builtinCall, count int) error {
+	if len(call.args) != count {
+		return newErrSemantic(call.name, "%v expects %d arguments; got %d", call.name.value, count, len(call.args))
+	}
+	return nil
+}
+
+// checkArg fails unless argument index of a call, counted from zero, is
+// valid. expected describes a valid argument.
+func checkArg(call *builtinCall, index int, valid bool, expected string) error {
+	if !valid {
+		return newErrSemantic(call.name, "argument %d of %v must be %v; got %v", index+1, call.name.value, expected, call.argTypes[index])
+	}
+	return nil
+}
+
+// evalArgs evaluates the arguments of a call.
+func evalArgs(i *interpreter, call *builtinCall) ([]interface{}, error) {
+	values := make([]interface{}, len(call.args))
+	for index, arg := range call.args {
+		value, err := i.visit(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[index] = value
+	}
+	return values, nil
+}
+
+func checkOrdinalArg(call *builtinCall) error {
+	if err := checkArgCount(call, 1); err != nil {
+		return err
+	}
+	if !isOrdinal(call.argTypes[0]) {
+		return newErrSemantic(call.name, "%v expects an ordinal argument; got %v", call.name.value, call.argTypes[0])
+	}
+	return nil
+}
+
+func checkOrd(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkOrdinalArg(call); err != nil {
+		return nil, err
+	}
+	return typeInt64, nil
+}
+
+func runOrd(i *interpreter, call *builtinCall) (interface{}, error) {
+	value, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	if isIntegerValue(value) {
+		return value, nil
+	}
+	return ordinalValue(value), nil
+}
+
+func checkSuccPred(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkOrdinalArg(call); err != nil {
+		return nil, err
+	}
+	return baseType(call.argTypes[0]), nil
+}
+
+// runSuccPred returns the successor or the predecessor of an ordinal value.
+// With range checks on, it fails beyond the bounds of the type. Otherwise
+// integers wrap around.
+func runSuccPred(i *interpreter, call *builtinCall) (interface{}, error) {
+	value, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := baseType(call.argTypes[0]).(*integerType); ok && t.unbounded() {
+		if call.name.value == "pred" {
+			return minus(value, 1), nil
+		}
+		return add(value, 1), nil
+	}
+	ord := ordinalValue(value) + 1
+	if call.name.value == "pred" {
+		ord -= 2
+	}
+	typ := baseType(call.argTypes[0])
+	result := ordinalToValue(typ, ord)
+	if call.switches.rangeChecks {
+		if err = checkRange(typ, result); err != nil {
+			return nil, err
+		}
+	}
+	return convertValue(typ, result), nil
+}
+
+func checkNewDispose(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkArgCount(call, 1); err != nil {
+		return nil, err
+	}
+	if pointer, ok := call.argTypes[0].(*pointerType); !ok || pointer == typeNil || !isVariable(call.args[0]) {
+		return nil, newErrSemantic(call.name, "%v expects a pointer variable", call.name.value)
+	}
+	return nil, nil
+}
+
+// runNew allocates a heap variable and points the argument to it.
+func runNew(i *interpreter, call *builtinCall) (interface{}, error) {
+	ref, err := i.reference(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	ref.set(i.heap.allocate(call.argTypes[0].(*pointerType).target))
+	return nil, nil
+}
+
+// runDispose frees the heap variable the argument points to. The pointer is
+// left dangling, as in Turbo Pascal.
+func runDispose(i *interpreter, call *builtinCall) (interface{}, error) {
+	pointer, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	return nil, i.heap.dispose(pointer.(*heapCell))
+}
+
+func checkLength(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkArgCount(call, 1); err != nil {
+		return nil, err
+	}
+	if isArray(call.argTypes[0]) {
+		return typeInt64, nil
+	}
+	if err := checkArg(call, 0, isText(call.argTypes[0]), "a string or an array"); err != nil {
+		return nil, err
+	}
+	return s.options.integer(), nil
+}
+
+// runLength returns the length of a string or the number of elements of an
+// array.
+func runLength(i *interpreter, call *builtinCall) (interface{}, error) {
+	value, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	if isArray(call.argTypes[0]) {
+		return len(arrayElements(value)), nil
+	}
+	return len(textValue(value)), nil
+}
+
+func checkCopy(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if len(call.args) > 0 {
+		if array, ok := call.argTypes[0].(*dynamicArrayType); ok {
+			return checkCopyArray(call, array)
+		}
+	}
+	if err := checkArgCount(call, 3); err != nil {
+		return nil, err
+	}
+	if err := checkArg(call, 0, isText(call.argTypes[0]), "a string"); err != nil {
+		return nil, err
+	}
+	for index := 1; index < 3; index++ {
+		if err := checkArg(call, index, isInteger(call.argTypes[index]), "an integer"); err != nil {
+			return nil, err
+		}
+	}
+	return typeString, nil
+}
+
+// runCopy returns count characters of a string starting at index. Like Turbo
+// Pascal, it returns as many characters as there are, and an empty string if
+// index is beyond the end of the string.
+func runCopy(i *interpreter, call *builtinCall) (interface{}, error) {
+	if _, ok := call.argTypes[0].(*dynamicArrayType); ok {
+		return runCopyArray(i, call)
+	}
+	args, err := evalArgs(i, call)
+	if err != nil {
+		return nil, err
+	}
+	s, index, count := textValue(args[0]), ordinalValue(args[1]), ordinalValue(args[2])
+	if index < 1 {
+		index = 1
+	}
+	if index > len(s) || count <= 0 {
+		return "", nil
+	}
+	if count > len(s)-index+1 {
+		count = len(s) - index + 1
+	}
+	return s[index-1 : index-1+count], nil
+}
+
+func checkPos(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkArgCount(call, 2); err != nil {
+		return nil, err
+	}
+	for index := 0; index < 2; index++ {
+		if err := checkArg(call, index, isText(call.argTypes[index]), "a string"); err != nil {
+			return nil, err
+		}
+	}
+	return s.options.integer(), nil
+}
+
+// runPos returns the index of the first occurrence of a substring in a
+// string, or 0 if there is none.
+func runPos(i *interpreter, call *builtinCall) (interface{}, error) {
+	args, err := evalArgs(i, call)
+	if err != nil {
+		return nil, err
+	}
+	substr, s := textValue(args[0]), textValue(args[1])
+	if substr == "" {
+		return 0, nil
+	}
+	return strings.Index(s, substr) + 1, nil
+}
+
+func checkConcat(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if len(call.args) == 0 {
+		return nil, newErrSemantic(call.name, "%v expects at least one argument", call.name.value)
+	}
+	for index := range call.args {
+		if err := checkArg(call, index, isText(call.argTypes[index]), "a string"); err != nil {
+			return nil, err
+		}
+	}
+	return typeString, nil
+}
+
+func runConcat(i *interpreter, call *builtinCall) (interface{}, error) {
+	args, err := evalArgs(i, call)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	for _, arg := range args {
+		b.WriteString(textValue(arg))
+	}
+	return convertValue(typeString, b.String()), nil
+}
+
+func checkUpCase(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkArgCount(call, 1); err != nil {
+		return nil, err
+	}
+	if err := checkArg(call, 0, isChar(call.argTypes[0]), "a character"); err != nil {
+		return nil, err
+	}
+	return typeChar, nil
+}
+
+func runUpCase(i *interpreter, call *builtinCall) (interface{}, error) {
+	value, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	c := value.(byte)
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	return c, nil
+}
+
+func checkChr(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkArgCount(call, 1); err != nil {
+		return nil, err
+	}
+	if err := checkArg(call, 0, isInteger(call.argTypes[0]), "an integer"); err != nil {
+		return nil, err
+	}
+	if value, ok := s.foldedValue(call.args[0]); ok && checkRange(typeChar, value) != nil {
+		return nil, newErrSemantic(call.name, "character code %v is out of range", value)
+	}
+	return typeChar, nil
+}
+
+// runChr returns the character with the given code. With range checks on, it
+// fails for codes beyond 0..255; otherwise the code wraps around.
+func runChr(i *interpreter, call *builtinCall) (interface{}, error) {
+	value, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	if call.switches.rangeChecks {
+		if err = checkRange(typeChar, value); err != nil {
+			return nil, err
+		}
+	}
+	return byte(ordinalValue(value)), nil
+}
+
+// checkStringVar checks that argument index of a call is a string variable,
+// which the call modifies.
+func checkStringVar(call *builtinCall, index int) error {
+	_, ok := call.argTypes[index].(*stringType)
+	return checkArg(call, index, ok && isVariable(call.args[index]), "a string variable")
+}
+
+func checkInsert(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkArgCount(call, 3); err != nil {
+		return nil, err
+	}
+	if err := checkArg(call, 0, isText(call.argTypes[0]), "a string"); err != nil {
+		return nil, err
+	}
+	if err := checkStringVar(call, 1); err != nil {
+		return nil, err
+	}
+	if err := checkArg(call, 2, isInteger(call.argTypes[2]), "an integer"); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// runInsert inserts a string into a string variable before index. The
+// source is appended if index is beyond the end of the variable, and the
+// result is truncated to the size of the variable.
+func runInsert(i *interpreter, call *builtinCall) (interface{}, error) {
+	args, err := evalArgs(i, call)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := i.reference(call.args[1])
+	if err != nil {
+		return nil, err
+	}
+	source, s, index := textValue(args[0]), args[1].(string), ordinalValue(args[2])
+	if index < 1 {
+		index = 1
+	}
+	if index > len(s) {
+		index = len(s) + 1
+	}
+	ref.set(convertValue(call.argTypes[1], s[:index-1]+source+s[index-1:]))
+	return nil, nil
+}
+
+func checkDelete(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkArgCount(call, 3); err != nil {
+		return nil, err
+	}
+	if err := checkStringVar(call, 0); err != nil {
+		return nil, err
+	}
+	for index := 1; index < 3; index++ {
+		if err := checkArg(call, index, isInteger(call.argTypes[index]), "an integer"); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// runDelete removes count characters from a string variable starting at
+// index. Nothing is removed if index is outside the string.
+func runDelete(i *interpreter, call *builtinCall) (interface{}, error) {
+	args, err := evalArgs(i, call)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := i.reference(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	s, index, count := args[0].(string), ordinalValue(args[1]), ordinalValue(args[2])
+	if index < 1 || index > len(s) || count <= 0 {
+		return nil, nil
+	}
+	if count > len(s)-index+1 {
+		count = len(s) - index + 1
+	}
+	ref.set(s[:index-1] + s[index-1+count:])
+	return nil, nil
+}
+
+// checkFileVar checks that argument index of a call is a file variable.
+func checkFileVar(call *builtinCall, index int) error {
+	_, ok := call.argTypes[index].(*fileType)
+	return checkArg(call, index, ok && isVariable(call.args[index]), "a file variable")
+}
+
+// checkTextFileVar checks that argument index of a call is a variable of
+// type TEXT.
+func checkTextFileVar(call *builtinCall, index int) error {
+	return checkArg(call, index, call.argTypes[index] == typeText && isVariable(call.args[index]), "a text file variable")
+}
+
+// checkTypedFileVar checks that argument index of a call is a typed file
+// variable.
+func checkTypedFileVar(call *builtinCall, index int) error {
+	typ, ok := call.argTypes[index].(*fileType)
+	return checkArg(call, index, ok && typ.element != nil && isVariable(call.args[index]), "a typed file variable")
+}
+
+// hasFileArg returns true if the first argument of a call of an I/O routine
+// is a file.
+func hasFileArg(call *builtinCall) bool {
+	if len(call.args) == 0 {
+		return false
+	}
+	_, ok := call.argTypes[0].(*fileType)
+	return ok
+}
+
+// typedFileElement returns the component type of the file a call of an I/O
+// routine operates on, or nil if it is not a typed file.
+func typedFileElement(call *builtinCall) dataType {
+	if !hasFileArg(call) {
+		return nil
+	}
+	return call.argTypes[0].(*fileType).element
+}
+
+// textFileArg returns the file a call of a text I/O routine operates on and
+// the index of its first other argument. Without a file argument, the call
+// operates on def.
+func textFileArg(i *interpreter, call *builtinCall, def *textFile) (*textFile, int, error) {
+	if !hasFileArg(call) {
+		return def, 0, nil
+	}
+	f, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, 0, err
+	}
+	return f.(*textFile), 1, nil
+}
+
+// ioRoutine wraps the run function of an I/O routine. With I/O checks off,
+// an I/O error is kept for IOResult instead of stopping the program, and
+// I/O routines do nothing while an error is pending, as in Turbo Pascal.
+// Functions then return pending.
+func ioRoutine(run builtinRun, pending interface{}) builtinRun {
+	return func(i *interpreter, call *builtinCall) (interface{}, error) {
+		if call.switches.ioChecks {
+			return run(i, call)
+		}
+		if i.ioResult != 0 {
+			return pending, nil
+		}
+		value, err := run(i, call)
+		if r, ok := err.(*errRuntime); ok && isIOError(r.code) {
+			i.ioResult = r.code
+			return pending, nil
+		}
+		return value, err
+	}
+}
+
+func checkWrite(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if element := typedFileElement(call); element != nil {
+		return checkWriteTyped(s, call, element)
+	}
+	start := 0
+	if hasFileArg(call) {
+		if err := checkFileVar(call, 0); err != nil {
+			return nil, err
+		}
+		start = 1
+	}
+	for _, typ := range call.argTypes[start:] {
+		switch baseType(typ).(type) {
+		case *integerType, *realType, *decimalType, *booleanType, *charType, *stringType, *enumType:
+		default:
+			return nil, newErrSemantic(call.name, "cannot write %v", typ)
+		}
+	}
+	return nil, nil
+}
+
+// runWrite writes its arguments to a file or to the output of the
+// interpreter. WriteLn ends the line.
+func runWrite(i *interpreter, call *builtinCall) (interface{}, error) {
+	if typedFileElement(call) != nil {
+		return runWriteTyped(i, call)
+	}
+	f, start, err := textFileArg(i, call, i.stdout)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	for index := start; index < len(call.args); index++ {
+		arg := call.args[index]
+		width, decimals := 0, -1
+		if f, ok := arg.(*formatNode); ok {
+			value, err := i.visit(f.width)
+			if err != nil {
+				return nil, err
+			}
+			width = ordinalValue(value)
+			if f.decimals != nil {
+				if value, err = i.visit(f.decimals); err != nil {
+					return nil, err
+				}
+				if decimals = ordinalValue(value); decimals < 0 {
+					decimals = 0
+				}
+			}
+			arg = f.value
+		}
+		value, err := i.visit(arg)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(formatValue(call.argTypes[index], value, width, decimals))
+	}
+	if call.name.value == "writeln" {
+		b.WriteByte('\n')
+	}
+	return nil, f.write(b.String())
+}
+
+func checkRead(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if element := typedFileElement(call); element != nil {
+		return checkReadTyped(call, element)
+	}
+	start := 0
+	if hasFileArg(call) {
+		if err := checkFileVar(call, 0); err != nil {
+			return nil, err
+		}
+		start = 1
+	}
+	for index := start; index < len(call.args); index++ {
+		typ := call.argTypes[index]
+		switch baseType(typ).(type) {
+		case *integerType, *realType, *decimalType, *charType, *stringType:
+			if isVariable(call.args[index]) {
+				continue
+			}
+		}
+		return nil, newErrSemantic(call.name, "argument %d of %v must be a variable of a numeric, character or string type; got %v", index+1, call.name.value, typ)
+	}
+	return nil, nil
+}
+
+// runRead reads values from a file or from the input of the interpreter
+// into its arguments. ReadLn then skips the rest of the line.
+func runRead(i *interpreter, call *builtinCall) (interface{}, error) {
+	if typedFileElement(call) != nil {
+		return runReadTyped(i, call)
+	}
+	f, start, err := textFileArg(i, call, i.stdin)
+	if err != nil {
+		return nil, err
+	}
+	r, err := f.input()
+	if err != nil {
+		return nil, err
+	}
+	for index := start; index < len(call.args); index++ {
+		ref, err := i.reference(call.args[index])
+		if err != nil {
+			return nil, err
+		}
+		typ := call.argTypes[index]
+		var value interface{}
+		switch t := baseType(typ).(type) {
+		case *integerType:
+			value, err = readInteger(r, t.unbounded())
+		case *realType:
+			value, err = readReal(r)
+		case *decimalType:
+			value, err = readDecimal(r)
+		case *charType:
+			value, err = readChar(r)
+		default:
+			value, err = readString(r)
+		}
+		if err != nil {
+			return nil, readError(err)
+		}
+		if call.switches.rangeChecks {
+			if err = checkRange(typ, value); err != nil {
+				return nil, err
+			}
+		}
+		ref.set(convertValue(typ, value))
+	}
+	if call.name.value == "readln" {
+		if err := skipLine(r); err != nil {
+			return nil, readError(err)
+		}
+	}
+	return nil, nil
+}
+
+func checkAssign(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkArgCount(call, 2); err != nil {
+		return nil, err
+	}
+	if err := checkFileVar(call, 0); err != nil {
+		return nil, err
+	}
+	if err := checkArg(call, 1, isText(call.argTypes[1]), "a string"); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// runAssign sets the name of the file a file variable is opened with.
+func runAssign(i *interpreter, call *builtinCall) (interface{}, error) {
+	args, err := evalArgs(i, call)
+	if err != nil {
+		return nil, err
+	}
+	args[0].(pascalFile).assign(textValue(args[1]))
+	return nil, nil
+}
+
+func checkFileProc(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkArgCount(call, 1); err != nil {
+		return nil, err
+	}
+	if call.name.value == "append" {
+		return nil, checkTextFileVar(call, 0)
+	}
+	return nil, checkFileVar(call, 0)
+}
+
+// runFileProc runs Reset, Rewrite, Append or Close.
+func runFileProc(i *interpreter, call *builtinCall) (interface{}, error) {
+	value, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	f := value.(pascalFile)
+	switch call.name.value {
+	case "reset":
+		return nil, f.reset(i.files)
+	case "rewrite":
+		return nil, f.rewrite(i.files)
+	case "append":
+		return nil, f.(*textFile).append(i.files)
+	default:
+		return nil, f.close()
+	}
+}
+
+func checkEof(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if len(call.args) > 1 {
+		return nil, newErrSemantic(call.name, "%v expects at most one argument; got %d", call.name.value, len(call.args))
+	}
+	if len(call.args) == 1 {
+		check := checkFileVar
+		if call.name.value == "eoln" {
+			check = checkTextFileVar
+		}
+		if err := check(call, 0); err != nil {
+			return nil, err
+		}
+	}
+	return typeBoolean, nil
+}
+
+// runEof runs Eof or Eoln, on a file or on the input of the interpreter.
+func runEof(i *interpreter, call *builtinCall) (interface{}, error) {
+	if !hasFileArg(call) {
+		if call.name.value == "eoln" {
+			return i.stdin.eoln()
+		}
+		return i.stdin.eof()
+	}
+	f, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	if call.name.value == "eoln" {
+		return f.(*textFile).eoln()
+	}
+	return f.(pascalFile).eof()
+}
+
+func checkIOResult(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkArgCount(call, 0); err != nil {
+		return nil, err
+	}
+	return s.options.integer(), nil
+}
+
+// runIOResult returns the code of the last I/O error and clears it.
+func runIOResult(i *interpreter, call *builtinCall) (interface{}, error) {
+	result := i.ioResult
+	i.ioResult = 0
+	return result, nil
+}
+
+func checkWriteTyped(s *semanticAnalyzer, call *builtinCall, element dataType) (dataType, error) {
+	if call.name.value == "writeln" {
+		return nil, newErrSemantic(call.name, "%v expects a text file", call.name.value)
+	}
+	if err := checkTypedFileVar(call, 0); err != nil {
+		return nil, err
+	}
+	for index := 1; index < len(call.args); index++ {
+		if _, ok := call.args[index].(*formatNode); ok {
+			return nil, newErrSemantic(call.name, "field widths are only allowed for text files")
+		}
+		if err := checkArg(call, index, isAssignable(element, call.argTypes[index]), element.String()); err != nil {
+			return nil, err
+		}
+		// Components known at compile time must fit, as Write would store
+		// them truncated.
+		if value, ok := s.foldedValue(call.args[index]); ok {
+			if err := checkConstantRange(call.name, element, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, nil
+}
+
+// runWriteTyped writes components to a typed file. With range checks on, it
+// fails for components out of the range of the component type.
+func runWriteTyped(i *interpreter, call *builtinCall) (interface{}, error) {
+	args, err := evalArgs(i, call)
+	if err != nil {
+		return nil, err
+	}
+	f := args[0].(*typedFile)
+	for _, arg := range args[1:] {
+		if call.switches.rangeChecks {
+			if err = checkRange(f.element, arg); err != nil {
+				return nil, err
+			}
+		}
+		if err = f.write(convertValue(f.element, arg)); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func checkReadTyped(call *builtinCall, element dataType) (dataType, error) {
+	if call.name.value == "readln" {
+		return nil, newErrSemantic(call.name, "%v expects a text file", call.name.value)
+	}
+	if err := checkTypedFileVar(call, 0); err != nil {
+		return nil, err
+	}
+	for index := 1; index < len(call.args); index++ {
+		valid := isVariable(call.args[index]) && isAssignable(call.argTypes[index], element)
+		if err := checkArg(call, index, valid, "a variable of type "+element.String()); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// runReadTyped reads components of a typed file into variables.
+func runReadTyped(i *interpreter, call *builtinCall) (interface{}, error) {
+	value, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	f := value.(*typedFile)
+	for index, arg := range call.args[1:] {
+		ref, err := i.reference(arg)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = f.read(); err != nil {
+			return nil, err
+		}
+		typ := call.argTypes[index+1]
+		if call.switches.rangeChecks {
+			if err = checkRange(typ, value); err != nil {
+				return nil, err
+			}
+		}
+		ref.set(convertValue(typ, value))
+	}
+	return nil, nil
+}
+
+func checkSeek(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkArgCount(call, 2); err != nil {
+		return nil, err
+	}
+	if err := checkTypedFileVar(call, 0); err != nil {
+		return nil, err
+	}
+	return nil, checkArg(call, 1, isInteger(call.argTypes[1]), "an integer")
+}
+
+// runSeek moves to a component of a typed file, counted from 0.
+func runSeek(i *interpreter, call *builtinCall) (interface{}, error) {
+	args, err := evalArgs(i, call)
+	if err != nil {
+		return nil, err
+	}
+	return nil, args[0].(*typedFile).seek(ordinalValue(args[1]))
+}
+
+func checkTypedFileRoutine(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkArgCount(call, 1); err != nil {
+		return nil, err
+	}
+	if err := checkTypedFileVar(call, 0); err != nil {
+		return nil, err
+	}
+	if call.name.value == "truncate" {
+		return nil, nil
+	}
+	return typeInt64, nil
+}
+
+// runTypedFileRoutine runs FilePos, FileSize or Truncate.
+func runTypedFileRoutine(i *interpreter, call *builtinCall) (interface{}, error) {
+	value, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	f := value.(*typedFile)
+	switch call.name.value {
+	case "filepos":
+		return f.pos()
+	case "filesize":
+		return f.size()
+	default:
+		return nil, f.truncate()
+	}
+}
@@ -0,0 +1,159 @@
+package go_pascal
+
+// activationRecord holds the variables of one invocation of the program or a
+// routine.
+type activationRecord struct {
+	name    string
+	level   int
+	members map[string]interface{}
+	// access is the record of the lexically enclosing routine, which gives
+	// nested routines access to the variables of their parents.
+	access *activationRecord
+}
+
+func newActivationRecord(name string, level int, access *activationRecord) *activationRecord {
+	return &activationRecord{
+		name:    name,
+		level:   level,
+		members: make(map[string]interface{}),
+		access:  access,
+	}
+}
+
+func (ar *activationRecord) get(name string) (interface{}, bool) {
+	value, ok := ar.members[name]
+	return value, ok
+}
+
+func (ar *activationRecord) set(name string, value interface{}) {
+	ar.members[name] = value
+}
+
+// enclosing follows the access links to the record at the given nesting
+// level.
+func (ar *activationRecord) enclosing(level int) *activationRecord {
+	for ar.level > level {
+		ar = ar.access
+	}
+	return ar
+}
+
+// routineValue is a routine taken as a value of a procedural type. access is
+// the record of the routine's parent at the time the value was taken, so a
+// nested routine still reaches the variables of its parent when it is
+// called through a variable or a parameter.
+type routineValue struct {
+	symbol *routineSymbol
+	access *activationRecord
+}
+
+// equal returns true if r and other are the same routine with the same
+// parent, or both NIL.
+func (r *routineValue) equal(other *routineValue) bool {
+	if r == nil || other == nil {
+		return r == other
+	}
+	return r.symbol == other.symbol && r.access == other.access
+}
+
+// maxCallDepth limits the recursion of Pascal routines, which would
+// otherwise exhaust the Go stack.
+const maxCallDepth = 10000
+
+type callStack struct {
+	records []*activationRecord
+}
+
+func newCallStack() *callStack {
+	return &callStack{}
+}
+
+func (s *callStack) push(ar *activationRecord) error {
+	if len(s.records) >= maxCallDepth {
+		return newErrRuntime(errCodeStackOverflow, "stack overflow in %v", ar.name)
+	}
+	s.records = append(s.records, ar)
+	return nil
+}
+
+func (s *callStack) pop() *activationRecord {
+	ar := s.records[len(s.records)-1]
+	s.records = s.records[:len(s.records)-1]
+	return ar
+}
+
+func (s *callStack) peek() *activationRecord {
+	return s.records[len(s.records)-1]
+}
+
+// reference refers to the storage of a variable. VAR parameters hold a
+// reference to the variable passed by the caller.
+type reference interface {
+	get() interface{}
+	set(interface{})
+}
+
+type memberReference struct {
+	ar   *activationRecord
+	name string
+}
+
+func newMemberReference(ar *activationRecord, name string) reference {
+	return &memberReference{
+		ar:   ar,
+		name: name,
+	}
+}
+
+func (r *memberReference) get() interface{} {
+	return r.ar.members[r.name]
+}
+
+func (r *memberReference) set(value interface{}) {
+	r.ar.members[r.name] = value
+}
+
+// charReference refers to a character of a string variable. Go strings are
+// immutable, so setting the character replaces the whole string.
+type charReference struct {
+	str    reference
+	offset int
+}
+
+func newCharReference(str reference, offset int) reference {
+	return &charReference{
+		str:    str,
+		offset: offset,
+	}
+}
+
+func (r *charReference) get() interface{} {
+	return r.str.get().(string)[r.offset]
+}
+
+func (r *charReference) set(value interface{}) {
+	s := []byte(r.str.get().(string))
+	s[r.offset] = value.(byte)
+	r.str.set(string(s))
+}
+
+// elementReference refers to an element of an array.
+type elementReference struct {
+	elements []interface{}
+	offset   int
+}
+
+func newElementReference(elements []interface{}, offset int) reference {
+	return &elementReference{
+		elements: elements,
+		offset:   offset,
+	}
+}
+
+func (r *elementReference) get() interface{} {
+	return r.elements[r.offset]
+}
+
+func (r *elementReference) set(value interface{}) {
+	r.elements[r.offset] = value
+}
@@ -0,0 +1,39 @@
+package go_pascal
+
+import "math/cmplx"
+
+// isComplexValue returns true for the values of COMPLEX.
+func isComplexValue(v interface{}) bool {
+	_, ok := v.(complex128)
+	return ok
+}
+
+// complexOperands returns true if an operation on numbers of types a and b
+// is a complex operation, i.e. one of them is COMPLEX and the other one is
+// COMPLEX or a number, which is promoted to complex.
+func complexOperands(a, b dataType) bool {
+	return (isComplex(a) || isComplex(b)) && (isComplex(a) || isNumeric(a)) && (isComplex(b) || isNumeric(b))
+}
+
+// toComplex promotes a number to complex.
+func toComplex(v interface{}) complex128 {
+	if z, ok := v.(complex128); ok {
+		return z
+	}
+	return complex(toFloat(v), 0)
+}
+
+func isFiniteComplex(z complex128) bool {
+	return !cmplx.IsInf(z) && !cmplx.IsNaN(z)
+}
+
+// checkComplex checks the result of an operation on finite complex numbers
+// as checkFloat checks each of its parts.
+func checkComplex(mask fpuExceptions, z complex128) (interface{}, error) {
+	for _, part := range []float64{real(z), imag(z)} {
+		if _, err := checkFloat(mask, part); err != nil {
+			return nil, err
+		}
+	}
+	return z, nil
+}
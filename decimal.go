@@ -0,0 +1,210 @@
+package go_pascal
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// currency is a value of type CURRENCY: a fixed-point number with
+// currencyDecimals decimals, held as a 64-bit integer scaled by 10000, as
+// in Delphi.
+type currency int64
+
+const currencyDecimals = 4
+
+// decimalDivisionScale is the number of decimals a quotient of DECIMAL
+// values is rounded to if it has no exact decimal representation, like 1/3.
+const decimalDivisionScale = 28
+
+// decimal is a value of type DECIMAL: the number unscaled / 10^scale. The
+// scale is the number of decimals, which is arbitrary. Decimals are
+// immutable, so operations return new ones.
+type decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+// isDecimalValue returns true for the values of CURRENCY and DECIMAL.
+func isDecimalValue(v interface{}) bool {
+	switch v.(type) {
+	case currency, decimal:
+		return true
+	}
+	return false
+}
+
+// decimalOperands returns true if an operation on left and right is a
+// fixed-point operation, i.e. one of them is a CURRENCY or DECIMAL value.
+// Reals and integers are converted to decimals then.
+func decimalOperands(left, right interface{}) bool {
+	return isDecimalValue(left) || isDecimalValue(right)
+}
+
+// toDecimal converts a number to a decimal. A real is converted to the
+// shortest decimal that reads back as the same real, so 0.1 is exactly 0.1.
+// Reals that are not finite convert to 0.
+func toDecimal(v interface{}) decimal {
+	switch v := v.(type) {
+	case decimal:
+		return v
+	case currency:
+		return decimal{unscaled: big.NewInt(int64(v)), scale: currencyDecimals}
+	case float64:
+		if !isFinite(v) {
+			return decimal{unscaled: new(big.Int)}
+		}
+		d, _ := parseDecimal(strconv.FormatFloat(v, 'f', -1, 64))
+		return d
+	}
+	return decimal{unscaled: new(big.Int).Set(bigInt(v))}
+}
+
+// parseDecimal parses a decimal such as -12.50 exactly. The scale is the
+// number of digits after the point.
+func parseDecimal(s string) (decimal, bool) {
+	digits := s
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		digits = digits[1:]
+	}
+	scale := 0
+	if point := strings.IndexByte(digits, '.'); point >= 0 {
+		scale = len(digits) - point - 1
+		digits = digits[:point] + digits[point+1:]
+	}
+	if digits == "" || strings.IndexFunc(digits, func(c rune) bool { return c < '0' || c > '9' }) >= 0 {
+		return decimal{}, false
+	}
+	unscaled, _ := new(big.Int).SetString(digits, 10)
+	if strings.HasPrefix(s, "-") {
+		unscaled.Neg(unscaled)
+	}
+	return decimal{unscaled: unscaled, scale: scale}, true
+}
+
+// pow10 returns 10^n.
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// roundQuo returns n / m rounded to an integer. Halves are rounded to even
+// if halfEven is true, and away from zero otherwise.
+func roundQuo(n, m *big.Int, halfEven bool) *big.Int {
+	q, r := new(big.Int).QuoRem(n, m, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	c := new(big.Int).Abs(new(big.Int).Lsh(r, 1)).Cmp(new(big.Int).Abs(m))
+	if c > 0 || c == 0 && (!halfEven || q.Bit(0) != 0) {
+		if n.Sign() == m.Sign() {
+			q.Add(q, big.NewInt(1))
+		} else {
+			q.Sub(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// rescale returns d with the given number of decimals. Digits that do not
+// fit are rounded off as roundQuo does.
+func (d decimal) rescale(scale int, halfEven bool) decimal {
+	if scale >= d.scale {
+		return decimal{unscaled: new(big.Int).Mul(d.unscaled, pow10(scale-d.scale)), scale: scale}
+	}
+	return decimal{unscaled: roundQuo(d.unscaled, pow10(d.scale-scale), halfEven), scale: scale}
+}
+
+// align returns d and e with the same scale, the larger of both.
+func align(d, e decimal) (decimal, decimal) {
+	if d.scale < e.scale {
+		return d.rescale(e.scale, true), e
+	}
+	return d, e.rescale(d.scale, true)
+}
+
+func (d decimal) add(e decimal) decimal {
+	d, e = align(d, e)
+	return decimal{unscaled: new(big.Int).Add(d.unscaled, e.unscaled), scale: d.scale}
+}
+
+func (d decimal) sub(e decimal) decimal {
+	d, e = align(d, e)
+	return decimal{unscaled: new(big.Int).Sub(d.unscaled, e.unscaled), scale: d.scale}
+}
+
+// mul multiplies exactly, so the scale of the product is the sum of the
+// scales.
+func (d decimal) mul(e decimal) decimal {
+	return decimal{unscaled: new(big.Int).Mul(d.unscaled, e.unscaled), scale: d.scale + e.scale}
+}
+
+// quo divides d by e, which must not be zero. The quotient is rounded to
+// scale decimals, halves to even, and trailing zeros are dropped down to the
+// scale of d.
+func (d decimal) quo(e decimal, scale int) decimal {
+	if scale < d.scale {
+		scale = d.scale
+	}
+	n := new(big.Int).Mul(d.unscaled, pow10(scale-d.scale+e.scale))
+	q := decimal{unscaled: roundQuo(n, e.unscaled, true), scale: scale}
+	ten := big.NewInt(10)
+	for q.scale > d.scale {
+		quo, rem := new(big.Int).QuoRem(q.unscaled, ten, new(big.Int))
+		if rem.Sign() != 0 {
+			break
+		}
+		q = decimal{unscaled: quo, scale: q.scale - 1}
+	}
+	return q
+}
+
+func (d decimal) neg() decimal {
+	return decimal{unscaled: new(big.Int).Neg(d.unscaled), scale: d.scale}
+}
+
+func (d decimal) cmp(e decimal) int {
+	d, e = align(d, e)
+	return d.unscaled.Cmp(e.unscaled)
+}
+
+func (d decimal) sign() int {
+	return d.unscaled.Sign()
+}
+
+// float returns the real nearest to d.
+func (d decimal) float() float64 {
+	f, _ := new(big.Rat).SetFrac(d.unscaled, pow10(d.scale)).Float64()
+	return f
+}
+
+// String formats d with all of its decimals, such as -0.50.
+func (d decimal) String() string {
+	digits := new(big.Int).Abs(d.unscaled).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	}
+	if d.sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// toCurrency rounds d to the decimals of CURRENCY, halves to even. ok is
+// false if the result is out of the range of CURRENCY, in which case it is
+// the smallest CURRENCY value, like an out-of-range integer conversion of
+// the FPU.
+func (d decimal) toCurrency() (c currency, ok bool) {
+	unscaled := d.rescale(currencyDecimals, true).unscaled
+	if !unscaled.IsInt64() {
+		return currency(math.MinInt64), false
+	}
+	return currency(unscaled.Int64()), true
+}
+
+func (c currency) String() string {
+	return toDecimal(c).String()
+}
@@ -0,0 +1,157 @@
+package go_pascal
+
+import "strings"
+
+// dialect selects the Pascal dialect a program is written in.
+type dialect int
+
+const (
+	// dialectFPC is the default mode of Free Pascal.
+	dialectFPC dialect = iota
+	dialectTP
+	dialectObjFPC
+	dialectDelphi
+)
+
+var dialectNames = map[string]dialect{
+	"fpc":    dialectFPC,
+	"tp":     dialectTP,
+	"objfpc": dialectObjFPC,
+	"delphi": dialectDelphi,
+}
+
+// hasResult returns true if functions have an implicit Result variable.
+func (d dialect) hasResult() bool {
+	return d == dialectObjFPC || d == dialectDelphi
+}
+
+// integer returns the type INTEGER stands for. It is 16 bits wide in the
+// Turbo Pascal and FPC modes, and 32 bits wide in the ObjFPC and Delphi
+// modes.
+func (d dialect) integer() *integerType {
+	if d == dialectObjFPC || d == dialectDelphi {
+		return typeLongInt
+	}
+	return typeSmallInt
+}
+
+// evaluationTypes returns the types integer expressions may be evaluated
+// in, from the smallest to the largest. Turbo Pascal evaluates in 16 bits
+// unless an operand needs more, while Free Pascal evaluates in the native
+// 64 bits.
+func (d dialect) evaluationTypes() []*integerType {
+	if d == dialectTP {
+		return []*integerType{typeSmallInt, typeWord, typeLongInt, typeCardinal, typeInt64}
+	}
+	return []*integerType{typeInt64}
+}
+
+// switches are the compiler switches that can be turned on and off anywhere
+// in a program, such as {$R+}. Nodes that depend on them keep a copy of the
+// switches in effect where they were parsed.
+type switches struct {
+	// rangeChecks enables checking that values stored in variables of
+	// subrange and enumerated types are within the bounds of the type.
+	rangeChecks bool
+	// ioChecks makes I/O errors runtime errors. When it is off, I/O errors
+	// are reported by IOResult instead.
+	ioChecks bool
+	// overflowChecks makes integer arithmetic that overflows its type a
+	// runtime error. When it is off, results wrap around.
+	overflowChecks bool
+}
+
+// options controls how a program is compiled and run. The host sets them
+// before running a program, and compiler directives in the source override
+// them.
+type options struct {
+	dialect dialect
+	switches
+	// fpuMask holds the floating-point exceptions that give IEEE 754
+	// infinities and NaN instead of runtime errors. No exceptions are
+	// masked by default.
+	fpuMask fpuExceptions
+	// bigIntegers makes INTEGER a type without bounds, and evaluates all
+	// integer arithmetic in it, so it never overflows.
+	bigIntegers bool
+}
+
+func newOptions() *options {
+	return &options{
+		dialect:  dialectFPC,
+		switches: switches{ioChecks: true},
+	}
+}
+
+// integer returns the type INTEGER stands for.
+func (o *options) integer() *integerType {
+	if o.bigIntegers {
+		return typeBigInteger
+	}
+	return o.dialect.integer()
+}
+
+// evaluationTypes returns the types integer expressions may be evaluated
+// in, from the smallest to the largest.
+func (o *options) evaluationTypes() []*integerType {
+	if o.bigIntegers {
+		return []*integerType{typeBigInteger}
+	}
+	return o.dialect.evaluationTypes()
+}
+
+// applyDirective applies a compiler directive such as MODE DELPHI, i.e. the
+// text of a {$...} comment without the braces and the dollar sign. Unknown
+// directives are ignored.
+func (o *options) applyDirective(directive string) {
+	fields := strings.Fields(strings.ToLower(directive))
+	if len(fields) == 0 {
+		return
+	}
+	if o.applySwitches(fields[0]) {
+		return
+	}
+	switch fields[0] {
+	case "rangechecks":
+		if len(fields) > 1 {
+			o.rangeChecks = fields[1] == "on"
+		}
+	case "iochecks":
+		if len(fields) > 1 {
+			o.ioChecks = fields[1] == "on"
+		}
+	case "overflowchecks":
+		if len(fields) > 1 {
+			o.overflowChecks = fields[1] == "on"
+		}
+	case "mode":
+		if len(fields) > 1 {
+			if d, ok := dialectNames[fields[1]]; ok {
+				o.dialect = d
+			}
+		}
+	}
+}
+
+// applySwitches applies short switch directives such as r+ or r-,q+. It
+// returns false if the directive is not made of switches.
+func (o *options) applySwitches(directive string) bool {
+	settings := strings.Split(directive, ",")
+	for _, setting := range settings {
+		if len(setting) != 2 || setting[1] != '+' && setting[1] != '-' {
+			return false
+		}
+	}
+	for _, setting := range settings {
+		on := setting[1] == '+'
+		switch setting[0] {
+		case 'r':
+			o.rangeChecks = on
+		case 'i':
+			o.ioChecks = on
+		case 'q':
+			o.overflowChecks = on
+		}
+	}
+	return true
+}
@@ -0,0 +1,374 @@
+package go_pascal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// Turbo Pascal I/O error codes. With I/O checks off, they are reported by
+// IOResult instead of stopping the program.
+const (
+	errCodeFileNotFound  = 2
+	errCodePathNotFound  = 3
+	errCodeAccessDenied  = 5
+	errCodeNotAssigned   = 102
+	errCodeNotOpen       = 103
+	errCodeNotOpenInput  = 104
+	errCodeNotOpenOutput = 105
+)
+
+// isIOError returns true for the runtime error codes of I/O errors.
+func isIOError(code int) bool {
+	return code > 0 && code < 150
+}
+
+// fileType is a file type. Values of TEXT are *textFile and values of typed
+// files such as FILE OF INTEGER are *typedFile.
+type fileType struct {
+	name string
+	// element is the type of the components of a typed file, or nil for
+	// TEXT.
+	element dataType
+}
+
+func newFileType(element dataType) *fileType {
+	return &fileType{element: element}
+}
+
+func (t *fileType) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	return fmt.Sprintf("FILE OF %v", t.element)
+}
+
+var typeText = &fileType{name: "TEXT"}
+
+type fileMode int
+
+const (
+	fileClosed fileMode = iota
+	fileInput
+	fileOutput
+)
+
+// pascalFile is the value of a file variable.
+type pascalFile interface {
+	assign(name string)
+	reset(fsys fileSystem) error
+	rewrite(fsys fileSystem) error
+	close() error
+	// eof returns true if there is nothing left to read.
+	eof() (bool, error)
+}
+
+// fileName is the name Assign gives a file variable. assigned is false
+// until Assign is called.
+type fileName struct {
+	name     string
+	assigned bool
+}
+
+func (f *fileName) assign(name string) {
+	f.name, f.assigned = name, true
+}
+
+func (f *fileName) checkAssigned() error {
+	if !f.assigned {
+		return newErrRuntime(errCodeNotAssigned, "file is not assigned")
+	}
+	return nil
+}
+
+// textFile is the value of a text file variable. The standard input and
+// output of the interpreter are text files as well, which are always open.
+type textFile struct {
+	fileName
+	mode fileMode
+	// file is the open file, or nil for the standard input and output.
+	file   io.Closer
+	reader *bufio.Reader
+	writer io.Writer
+}
+
+func newInputFile(r io.Reader) *textFile {
+	return &textFile{
+		mode:   fileInput,
+		reader: bufio.NewReader(r),
+	}
+}
+
+func newOutputFile(w io.Writer) *textFile {
+	return &textFile{
+		mode:   fileOutput,
+		writer: w,
+	}
+}
+
+// fsErrorCode returns the I/O error code for an error of a fileSystem.
+func fsErrorCode(err error) int {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return errCodeFileNotFound
+	case errors.Is(err, fs.ErrInvalid):
+		return errCodePathNotFound
+	default:
+		return errCodeAccessDenied
+	}
+}
+
+// open opens the assigned file for reading, with Reset, or for writing,
+// with Rewrite and Append. An open file is closed first.
+func (f *textFile) open(fsys fileSystem, mode fileMode, flag int) error {
+	if err := f.checkAssigned(); err != nil {
+		return err
+	}
+	if f.mode != fileClosed {
+		if err := f.close(); err != nil {
+			return err
+		}
+	}
+	if mode == fileInput {
+		file, err := fsys.Open(f.name)
+		if err != nil {
+			return newErrRuntime(fsErrorCode(err), "%v", err)
+		}
+		f.file, f.reader = file, bufio.NewReader(file)
+	} else {
+		file, err := fsys.OpenFile(f.name, flag)
+		if err != nil {
+			return newErrRuntime(fsErrorCode(err), "%v", err)
+		}
+		f.file, f.writer = file, file
+	}
+	f.mode = mode
+	return nil
+}
+
+func (f *textFile) reset(fsys fileSystem) error {
+	return f.open(fsys, fileInput, 0)
+}
+
+func (f *textFile) rewrite(fsys fileSystem) error {
+	return f.open(fsys, fileOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+}
+
+func (f *textFile) append(fsys fileSystem) error {
+	return f.open(fsys, fileOutput, os.O_WRONLY|os.O_APPEND)
+}
+
+func (f *textFile) close() error {
+	if f.mode == fileClosed {
+		return newErrRuntime(errCodeNotOpen, "file is not open")
+	}
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+	}
+	f.mode, f.file, f.reader, f.writer = fileClosed, nil, nil, nil
+	if err != nil {
+		return newErrRuntime(errCodeAccessDenied, "%v", err)
+	}
+	return nil
+}
+
+// input returns the reader of a file open for reading.
+func (f *textFile) input() (*bufio.Reader, error) {
+	switch f.mode {
+	case fileInput:
+		return f.reader, nil
+	case fileOutput:
+		return nil, newErrRuntime(errCodeNotOpenInput, "file is not open for input")
+	}
+	return nil, newErrRuntime(errCodeNotOpen, "file is not open")
+}
+
+func (f *textFile) write(s string) error {
+	switch f.mode {
+	case fileOutput:
+		if _, err := io.WriteString(f.writer, s); err != nil {
+			return newErrRuntime(errCodeDiskWrite, "%v", err)
+		}
+		return nil
+	case fileInput:
+		return newErrRuntime(errCodeNotOpenOutput, "file is not open for output")
+	}
+	return newErrRuntime(errCodeNotOpen, "file is not open")
+}
+
+func (f *textFile) eof() (bool, error) {
+	r, err := f.input()
+	if err != nil {
+		return false, err
+	}
+	if _, err = r.Peek(1); err == io.EOF {
+		return true, nil
+	} else if err != nil {
+		return false, readError(err)
+	}
+	return false, nil
+}
+
+// eoln returns true at the end of a line and at the end of the file.
+func (f *textFile) eoln() (bool, error) {
+	r, err := f.input()
+	if err != nil {
+		return false, err
+	}
+	next, err := r.Peek(1)
+	if err == io.EOF {
+		return true, nil
+	} else if err != nil {
+		return false, readError(err)
+	}
+	return next[0] == '\n' || next[0] == '\r', nil
+}
+
+// typedFile is the value of a typed file variable such as FILE OF INTEGER.
+// Its components are stored in the layout described in binary.go. Reset and
+// Rewrite open typed files for both reading and writing.
+type typedFile struct {
+	fileName
+	element dataType
+	// file is nil while the file is closed.
+	file writableFile
+}
+
+func newTypedFile(element dataType) *typedFile {
+	return &typedFile{element: element}
+}
+
+func (f *typedFile) open(fsys fileSystem, flag int) error {
+	if err := f.checkAssigned(); err != nil {
+		return err
+	}
+	if f.file != nil {
+		if err := f.close(); err != nil {
+			return err
+		}
+	}
+	file, err := fsys.OpenFile(f.name, flag)
+	if err != nil {
+		return newErrRuntime(fsErrorCode(err), "%v", err)
+	}
+	f.file = file
+	return nil
+}
+
+func (f *typedFile) reset(fsys fileSystem) error {
+	return f.open(fsys, os.O_RDWR)
+}
+
+func (f *typedFile) rewrite(fsys fileSystem) error {
+	return f.open(fsys, os.O_RDWR|os.O_CREATE|os.O_TRUNC)
+}
+
+func (f *typedFile) close() error {
+	if f.file == nil {
+		return newErrRuntime(errCodeNotOpen, "file is not open")
+	}
+	err := f.file.Close()
+	f.file = nil
+	if err != nil {
+		return newErrRuntime(errCodeAccessDenied, "%v", err)
+	}
+	return nil
+}
+
+// componentSize returns the size of a component in bytes. It is at least 1
+// so that empty records do not make positions undefined.
+func (f *typedFile) componentSize() int64 {
+	if size := storedSize(f.element); size > 0 {
+		return int64(size)
+	}
+	return 1
+}
+
+func (f *typedFile) read() (interface{}, error) {
+	if f.file == nil {
+		return nil, newErrRuntime(errCodeNotOpen, "file is not open")
+	}
+	b := make([]byte, storedSize(f.element))
+	if _, err := io.ReadFull(f.file, b); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, newErrRuntime(errCodeDiskRead, "read beyond end of file")
+	} else if err != nil {
+		return nil, readError(err)
+	}
+	value, _ := decodeValue(b, f.element)
+	return value, nil
+}
+
+func (f *typedFile) write(v interface{}) error {
+	if f.file == nil {
+		return newErrRuntime(errCodeNotOpen, "file is not open")
+	}
+	if _, err := f.file.Write(encodeValue(nil, f.element, v)); err != nil {
+		return newErrRuntime(errCodeDiskWrite, "%v", err)
+	}
+	return nil
+}
+
+// pos returns the number of the current component, counted from 0.
+func (f *typedFile) pos() (int, error) {
+	if f.file == nil {
+		return 0, newErrRuntime(errCodeNotOpen, "file is not open")
+	}
+	offset, err := f.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, readError(err)
+	}
+	return int(offset / f.componentSize()), nil
+}
+
+// size returns the number of components in the file.
+func (f *typedFile) size() (int, error) {
+	if f.file == nil {
+		return 0, newErrRuntime(errCodeNotOpen, "file is not open")
+	}
+	info, err := f.file.Stat()
+	if err != nil {
+		return 0, readError(err)
+	}
+	return int(info.Size() / f.componentSize()), nil
+}
+
+// seek moves to component n. It may move to the end of the file, but not
+// beyond it.
+func (f *typedFile) seek(n int) error {
+	size, err := f.size()
+	if err != nil {
+		return err
+	}
+	if n < 0 || n > size {
+		return newErrRuntime(errCodeDiskRead, "seek to component %d of a file of %d components", n, size)
+	}
+	if _, err = f.file.Seek(int64(n)*f.componentSize(), io.SeekStart); err != nil {
+		return readError(err)
+	}
+	return nil
+}
+
+// truncate removes the components from the current one to the end.
+func (f *typedFile) truncate() error {
+	pos, err := f.pos()
+	if err != nil {
+		return err
+	}
+	if err = f.file.Truncate(int64(pos) * f.componentSize()); err != nil {
+		return newErrRuntime(errCodeDiskWrite, "%v", err)
+	}
+	return nil
+}
+
+func (f *typedFile) eof() (bool, error) {
+	pos, err := f.pos()
+	if err != nil {
+		return false, err
+	}
+	size, err := f.size()
+	return pos >= size, err
+}
@@ -0,0 +1,227 @@
+package go_pascal
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// fileSystem is the filesystem programs open files in. It extends fs.FS,
+// which only reads files, with writing. Names are slash-separated paths as
+// accepted by fs.ValidPath.
+type fileSystem interface {
+	fs.FS
+	// OpenFile opens a file for writing with the flags of os.OpenFile, such
+	// as os.O_CREATE, os.O_TRUNC and os.O_APPEND.
+	OpenFile(name string, flag int) (writableFile, error)
+}
+
+// writableFile is a file opened by fileSystem.OpenFile. *os.File is one.
+type writableFile interface {
+	fs.File
+	io.Writer
+	io.Seeker
+	Truncate(size int64) error
+}
+
+// dirFS is a fileSystem of the files under a directory of the host.
+type dirFS struct {
+	fs.FS
+	dir string
+}
+
+func newDirFS(dir string) fileSystem {
+	return &dirFS{
+		FS:  os.DirFS(dir),
+		dir: dir,
+	}
+}
+
+func (d *dirFS) OpenFile(name string, flag int) (writableFile, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return os.OpenFile(filepath.Join(d.dir, filepath.FromSlash(name)), flag, 0666)
+}
+
+// memFS is a fileSystem held in memory, for tests and for hosts that do not
+// let programs access their files. It has no directories.
+type memFS struct {
+	files map[string]*memData
+}
+
+// memData is the content of a file of a memFS.
+type memData struct {
+	name    string
+	data    []byte
+	modTime time.Time
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: make(map[string]*memData)}
+}
+
+// writeFile creates a file with the given content, replacing any existing
+// file.
+func (m *memFS) writeFile(name string, data []byte) {
+	m.files[name] = &memData{
+		name:    name,
+		data:    append([]byte(nil), data...),
+		modTime: time.Now(),
+	}
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	data, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memFile{data: data}, nil
+}
+
+func (m *memFS) OpenFile(name string, flag int) (writableFile, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	data, ok := m.files[name]
+	if !ok {
+		if flag&os.O_CREATE == 0 {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		m.writeFile(name, nil)
+		data = m.files[name]
+	}
+	if flag&os.O_TRUNC != 0 {
+		data.data = nil
+		data.modTime = time.Now()
+	}
+	return &memFile{
+		data:     data,
+		writable: flag&(os.O_WRONLY|os.O_RDWR) != 0,
+		append:   flag&os.O_APPEND != 0,
+	}, nil
+}
+
+// memFile is an open file of a memFS.
+type memFile struct {
+	data             *memData
+	offset           int64
+	writable, append bool
+	closed           bool
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.offset >= int64(len(f.data.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data.data[f.offset:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if !f.writable {
+		return 0, fs.ErrPermission
+	}
+	if f.append {
+		f.offset = int64(len(f.data.data))
+	}
+	if end := f.offset + int64(len(p)); end > int64(len(f.data.data)) {
+		f.data.data = append(f.data.data, make([]byte, end-int64(len(f.data.data)))...)
+	}
+	n := copy(f.data.data[f.offset:], p)
+	f.offset += int64(n)
+	f.data.modTime = time.Now()
+	return n, nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.data.data))
+	}
+	if offset < 0 {
+		return 0, fs.ErrInvalid
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+	if !f.writable {
+		return fs.ErrPermission
+	}
+	if size < 0 {
+		return fs.ErrInvalid
+	}
+	if size <= int64(len(f.data.data)) {
+		f.data.data = f.data.data[:size]
+	} else {
+		f.data.data = append(f.data.data, make([]byte, size-int64(len(f.data.data)))...)
+	}
+	f.data.modTime = time.Now()
+	return nil
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	if f.closed {
+		return nil, fs.ErrClosed
+	}
+	return memFileInfo{f.data}, nil
+}
+
+func (f *memFile) Close() error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+	f.closed = true
+	return nil
+}
+
+type memFileInfo struct {
+	data *memData
+}
+
+func (i memFileInfo) Name() string {
+	return path.Base(i.data.name)
+}
+
+func (i memFileInfo) Size() int64 {
+	return int64(len(i.data.data))
+}
+
+func (i memFileInfo) Mode() fs.FileMode {
+	return 0666
+}
+
+func (i memFileInfo) ModTime() time.Time {
+	return i.data.modTime
+}
+
+func (i memFileInfo) IsDir() bool {
+	return false
+}
+
+func (i memFileInfo) Sys() interface{} {
+	return nil
+}
@@ -0,0 +1,64 @@
+package go_pascal
+
+import "math"
+
+// fpuExceptions is a set of floating-point exceptions. The host can mask
+// exceptions in the options of the interpreter, like the exception mask of
+// the FPU. An exception that is not masked is a runtime error, and a masked
+// one gives the IEEE 754 result, an infinity or NaN, instead.
+type fpuExceptions int
+
+const (
+	// fpuInvalid is raised by operations without a defined result, such as
+	// 0 / 0 or Sqrt(-1). Its IEEE result is NaN.
+	fpuInvalid fpuExceptions = 1 << iota
+	// fpuDivideByZero is raised by dividing a non-zero number by zero. Its
+	// IEEE result is an infinity.
+	fpuDivideByZero
+	// fpuOverflow is raised by results too large for their type. Its IEEE
+	// result is an infinity.
+	fpuOverflow
+
+	fpuAllExceptions = fpuInvalid | fpuDivideByZero | fpuOverflow
+)
+
+// raise returns the runtime error for exception e, or nil if e is masked.
+func (mask fpuExceptions) raise(e fpuExceptions) error {
+	if mask&e != 0 {
+		return nil
+	}
+	switch e {
+	case fpuInvalid:
+		return newErrRuntime(errCodeInvalidFloat, "invalid floating point operation")
+	case fpuDivideByZero:
+		return newErrRuntime(errCodeDivisionByZero, "division by zero")
+	default:
+		return newErrRuntime(errCodeFloatOverflow, "floating point overflow")
+	}
+}
+
+// checkFloat checks the result of an operation on finite numbers. An
+// infinite result is an overflow and NaN is an invalid operation.
+func checkFloat(mask fpuExceptions, x float64) (interface{}, error) {
+	if math.IsInf(x, 0) {
+		if err := mask.raise(fpuOverflow); err != nil {
+			return nil, err
+		}
+	}
+	if math.IsNaN(x) {
+		if err := mask.raise(fpuInvalid); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+func isFinite(x float64) bool {
+	return !math.IsInf(x, 0) && !math.IsNaN(x)
+}
+
+// isNaN returns true if v is a real that is not a number.
+func isNaN(v interface{}) bool {
+	x, ok := v.(float64)
+	return ok && math.IsNaN(x)
+}
@@ -0,0 +1,96 @@
+package go_pascal
+
+import (
+	"fmt"
+	"sort"
+)
+
+// heapCell is a variable allocated by New. Pointer values are represented by
+// a *heapCell; NIL is a nil *heapCell. A disposed cell is only marked as
+// such, so that pointers still referring to it can be detected as dangling.
+type heapCell struct {
+	id       int
+	typ      dataType
+	value    interface{}
+	disposed bool
+}
+
+func (c *heapCell) String() string {
+	return fmt.Sprintf("#%d %v", c.id, c.typ)
+}
+
+// heap holds the variables allocated by a program that have not been
+// disposed, by id. Disposed cells are dropped, so the heap only grows with
+// the memory a program actually holds.
+type heap struct {
+	cells map[int]*heapCell
+	// lastID is the id of the last cell allocated.
+	lastID int
+}
+
+func newHeap() *heap {
+	return &heap{cells: map[int]*heapCell{}}
+}
+
+func (h *heap) allocate(typ dataType) *heapCell {
+	h.lastID++
+	cell := &heapCell{
+		id:    h.lastID,
+		typ:   typ,
+		value: zeroValue(typ),
+	}
+	h.cells[cell.id] = cell
+	return cell
+}
+
+func (h *heap) dispose(cell *heapCell) error {
+	if cell == nil {
+		return newErrRuntime(errCodeInvalidPointer, "cannot dispose NIL")
+	}
+	if cell.disposed {
+		return newErrRuntime(errCodeInvalidPointer, "%v is already disposed", cell)
+	}
+	cell.disposed = true
+	cell.value = nil
+	delete(h.cells, cell.id)
+	return nil
+}
+
+// leaks returns the cells that have not been disposed, in the order they
+// were allocated. Called after a program has finished, it reports the
+// memory the program leaked.
+func (h *heap) leaks() []*heapCell {
+	var cells []*heapCell
+	for _, cell := range h.cells {
+		cells = append(cells, cell)
+	}
+	sort.Slice(cells, func(a, b int) bool {
+		return cells[a].id < cells[b].id
+	})
+	return cells
+}
+
+// heapReference refers to a heap variable through a pointer.
+type heapReference struct {
+	cell *heapCell
+}
+
+// newHeapReference dereferences a pointer. It fails for NIL and for
+// dangling pointers.
+func newHeapReference(cell *heapCell) (reference, error) {
+	if cell == nil {
+		return nil, newErrRuntime(errCodeAccessViolation, "NIL pointer dereference")
+	}
+	if cell.disposed {
+		return nil, newErrRuntime(errCodeAccessViolation, "dangling pointer dereference: %v is disposed", cell)
+	}
+	return &heapReference{cell: cell}, nil
+}
+
+func (r *heapReference) get() interface{} {
+	return r.cell.value
+}
+
+func (r *heapReference) set(value interface{}) {
+	r.cell.value = value
+}
@@ -2,7 +2,9 @@ package go_pascal
 
 import (
 	"fmt"
-	"reflect"
+	"io"
+	"math/big"
+	"os"
 )
 
 type errUndefinedIdentifier string
@@ -16,20 +18,155 @@ func newErrUndefinedIdentifier(id string) error {
 	return &err
 }
 
-// visitor knows how to visit every ast node.
-type visitor interface {
-	visit(node) (interface{}, error)
+// Turbo Pascal runtime error codes.
+const (
+	errCodeDiskRead       = 100
+	errCodeDiskWrite      = 101
+	errCodeInvalidNumeric = 106
+	errCodeDivisionByZero = 200
+	errCodeRangeCheck     = 201
+	errCodeStackOverflow  = 202
+	errCodeInvalidPointer = 204
+	errCodeFloatOverflow  = 205
+	errCodeInvalidFloat   = 207
+	errCodeOverflow       = 215
+	// errCodeAccessViolation is reported for NIL and dangling pointers.
+	errCodeAccessViolation = 216
+)
+
+type errRuntime struct {
+	// code is the Turbo Pascal runtime error code, or 0 for errors Turbo
+	// Pascal does not detect.
+	code int
+	msg  string
+	// pos is the position of the statement or expression that failed, if
+	// known.
+	pos position
+}
+
+func (err *errRuntime) Error() string {
+	s := "runtime error"
+	if err.code != 0 {
+		s += fmt.Sprintf(" %d", err.code)
+	}
+	if err.pos.line != 0 {
+		s += fmt.Sprintf(" at %v", err.pos)
+	}
+	return fmt.Sprintf("%v: %v", s, err.msg)
+}
+
+func newErrRuntime(code int, format string, args ...interface{}) error {
+	return &errRuntime{
+		code: code,
+		msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+// locate sets the position of a runtime error to the position of t, unless
+// the error already has one.
+func locate(err error, t *token) error {
+	if r, ok := err.(*errRuntime); ok && r.pos.line == 0 && t != nil {
+		r.pos = t.pos
+	}
+	return err
+}
+
+// checkRange checks that an ordinal value is within the bounds of type t, or
+// that the elements of a set value are within the base type of t.
+func checkRange(t dataType, value interface{}) error {
+	if members, ok := value.(set); ok {
+		if setT, ok := t.(*setType); ok && setT.base != nil {
+			return checkSetRange(setT, members)
+		}
+		return nil
+	}
+	bounds, ok := ordinalBounds(t)
+	if !ok {
+		return nil
+	}
+	if b, ok := value.(*big.Int); ok {
+		return newErrRuntime(errCodeRangeCheck, "range check error: %v is out of range for %v", b, t)
+	}
+	if ord := ordinalValue(value); !bounds.contains(ord) {
+		return newErrRuntime(errCodeRangeCheck, "range check error: %v is out of range for %v", ordinalString(t, ord), t)
+	}
+	return nil
+}
+
+// checkSetRange checks that the elements of a set value are within the base
+// type of set type t.
+func checkSetRange(t *setType, members set) error {
+	if ord, ok := elementOutOfRange(t, members); ok {
+		return newErrRuntime(errCodeRangeCheck, "range check error: %v is out of range for %v", ordinalString(t.base, ord), t.base)
+	}
+	return nil
+}
+
+// elementOutOfRange returns the first element of a set value that is not
+// within the base type of set type t, if there is one.
+func elementOutOfRange(t *setType, members set) (int, bool) {
+	bounds, _ := ordinalBounds(t.base)
+	for ord := 0; ord <= maxSetOrdinal; ord++ {
+		if members.contains(ord) && !bounds.contains(ord) {
+			return ord, true
+		}
+	}
+	return 0, false
+}
+
+type errTypeMismatch struct {
+	expected tokenType
+	value    interface{}
+}
+
+func (err *errTypeMismatch) Error() string {
+	return fmt.Sprintf("type mismatch: expected %v; got %v", err.expected, err.value)
+}
+
+func newErrTypeMismatch(expected tokenType, value interface{}) error {
+	return &errTypeMismatch{
+		expected: expected,
+		value:    value,
+	}
 }
 
 type interpreter struct {
-	globalScope map[string]interface{}
-	parser      *parser
+	// options may be changed by the host before the program is run.
+	options   *options
+	callStack *callStack
+	// globalScope is the activation record of the program. It is kept after
+	// the program has finished.
+	globalScope *activationRecord
+	// heap holds the variables allocated by New. It is kept after the
+	// program has finished, so that leaks can be reported.
+	heap *heap
+	// output and input are used by Write and Read without a file argument.
+	// They default to the standard output and input of the process, and may
+	// be changed by the host before the program is run.
+	output io.Writer
+	input  io.Reader
+	// files is the filesystem files are opened in. It defaults to an empty
+	// memFS, so programs cannot touch the files of the host unless it sets
+	// a dirFS.
+	files fileSystem
+	// stdout and stdin wrap output and input while the program runs.
+	stdout, stdin *textFile
+	// ioResult is the code of the last I/O error while I/O checks are off.
+	// It is reset by IOResult.
+	ioResult int
+	parser   *parser
 }
 
 func newInterpreter(input string) *interpreter {
+	p := newParser(input)
 	return &interpreter{
-		globalScope: make(map[string]interface{}),
-		parser:      newParser(input),
+		options:   p.lexer.options,
+		callStack: newCallStack(),
+		heap:      newHeap(),
+		output:    os.Stdout,
+		input:     os.Stdin,
+		files:     newMemFS(),
+		parser:    p,
 	}
 }
 
@@ -38,6 +175,10 @@ func (i *interpreter) walk() error {
 	if err != nil {
 		return err
 	}
+	if err = newSemanticAnalyzer(i.options).analyze(root); err != nil {
+		return err
+	}
+	i.stdin, i.stdout = newInputFile(i.input), newOutputFile(i.output)
 	if _, err = i.visit(root); err != nil {
 		return err
 	}
@@ -45,17 +186,101 @@ func (i *interpreter) walk() error {
 }
 
 func (i *interpreter) visit(n node) (interface{}, error) {
-	nodeTypeName := reflect.TypeOf(n).Elem().Name()
-	methodName := fmt.Sprintf("Visit%c%s", nodeTypeName[0]+'A'-'a', nodeTypeName[1:])
-	returnValues := reflect.ValueOf(i).MethodByName(methodName).Call([]reflect.Value{reflect.ValueOf(n)})
-	if returnValues[1].Interface() == nil {
-		return returnValues[0].Interface(), nil
+	return dispatch(i, n)
+}
+
+// evaluateConstant evaluates a constant expression at compile time.
+func evaluateConstant(options *options, n node) (interface{}, error) {
+	i := &interpreter{options: options}
+	return i.visit(n)
+}
+
+// record returns the activation record holding the variables declared at
+// the given nesting level.
+func (i *interpreter) record(level int) *activationRecord {
+	return i.callStack.peek().enclosing(level)
+}
+
+func (i *interpreter) readVariable(sym *varSymbol) (interface{}, bool) {
+	value, ok := i.record(sym.level).get(sym.name)
+	if ok && sym.byRef {
+		return value.(reference).get(), true
 	}
-	return returnValues[0].Interface(), returnValues[1].Interface().(error)
+	return value, ok
+}
+
+// reference returns a reference to the storage of an analyzed variable
+// access such as v or a[i].
+func (i *interpreter) reference(n node) (reference, error) {
+	switch r := n.(type) {
+	case *varNode:
+		if r.field != nil {
+			return i.reference(r.field)
+		}
+		return i.variableReference(r.symbol), nil
+	case *derefNode:
+		pointer, err := i.visit(r.pointer)
+		if err != nil {
+			return nil, err
+		}
+		ref, err := newHeapReference(pointer.(*heapCell))
+		return ref, locate(err, r.t)
+	case *fieldNode:
+		record, err := i.reference(r.record)
+		if err != nil {
+			return nil, err
+		}
+		return newElementReference(record.get().([]interface{}), r.symbol.index), nil
+	case *indexNode:
+		array, err := i.reference(r.array)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := r.typ.(*stringType); ok {
+			offset, err := i.stringOffset(r, array.get().(string))
+			if err != nil {
+				return nil, err
+			}
+			return newCharReference(array, offset), nil
+		}
+		if _, ok := r.typ.(*dynamicArrayType); ok {
+			elements := arrayElements(array.get())
+			offset, err := i.dynamicOffset(r, len(elements))
+			if err != nil {
+				return nil, err
+			}
+			return newElementReference(elements, offset), nil
+		}
+		offset, err := i.arrayOffset(r)
+		if err != nil {
+			return nil, err
+		}
+		return newElementReference(array.get().([]interface{}), offset), nil
+	}
+	panic(fmt.Sprintf("%T is not a variable", n))
+}
+
+func (i *interpreter) writeVariable(sym *varSymbol, value interface{}) {
+	i.variableReference(sym).set(value)
+}
+
+// variableReference returns a reference to the storage of a variable.
+func (i *interpreter) variableReference(sym *varSymbol) reference {
+	ar := i.record(sym.level)
+	if sym.byRef {
+		value, _ := ar.get(sym.name)
+		return value.(reference)
+	}
+	return newMemberReference(ar, sym.name)
 }
 
 func (i *interpreter) VisitProgramNode(n node) (interface{}, error) {
 	r := n.(*programNode)
+	i.globalScope = newActivationRecord(r.name.value.(string), 1, nil)
+	if err := i.callStack.push(i.globalScope); err != nil {
+		return nil, err
+	}
+	defer i.callStack.pop()
 	return i.visit(r.block)
 }
 
@@ -91,17 +316,248 @@ func (i *interpreter) VisitCompoundNode(n node) (interface{}, error) {
 }
 
 func (i *interpreter) VisitVarDeclNode(n node) (interface{}, error) {
-	//r := n.(*varDeclNode)
+	r := n.(*varDeclNode)
+	sym := r.varNode.(*varNode).symbol
+	if r.value != nil {
+		i.callStack.peek().set(sym.name, copyValue(r.initialValue))
+	} else {
+		i.callStack.peek().set(sym.name, zeroValue(sym.typ))
+	}
+	return nil, nil
+}
+
+func (i *interpreter) VisitConstDeclNode(n node) (interface{}, error) {
 	return nil, nil
 }
 
+func (i *interpreter) VisitRoutineDeclNode(n node) (interface{}, error) {
+	return nil, nil
+}
+
+// call evaluates the arguments in the caller's activation record and runs the
+// routine body in a new one, whose access link is access, the record of the
+// routine's parent. VAR parameters are bound to a reference to the variable
+// passed.
+func (i *interpreter) call(sym *routineSymbol, access *activationRecord, args []node, switches switches) (interface{}, error) {
+	ar := newActivationRecord(sym.name, sym.level+1, access)
+	for index, param := range sym.params {
+		arg := args[index]
+		if param.byRef {
+			ref, err := i.reference(arg)
+			if err != nil {
+				return nil, err
+			}
+			ar.set(param.name, ref)
+			continue
+		}
+		value, err := i.visit(arg)
+		if err != nil {
+			return nil, err
+		}
+		if switches.rangeChecks {
+			if err = checkRange(param.typ, value); err != nil {
+				return nil, err
+			}
+		}
+		if typ, ok := param.typ.(*decimalType); ok {
+			if value, err = i.convertDecimal(typ, value, nil); err != nil {
+				return nil, err
+			}
+		}
+		ar.set(param.name, copyValue(convertValue(param.typ, value)))
+	}
+	if sym.isFunction() {
+		switch sym.result.typ.(type) {
+		case *arrayType, *recordType:
+			// Elements and fields of the result can be assigned one by one.
+			ar.set(sym.result.name, zeroValue(sym.result.typ))
+		}
+	}
+
+	if err := i.callStack.push(ar); err != nil {
+		return nil, err
+	}
+	defer i.callStack.pop()
+	if _, err := i.visit(sym.block); err != nil {
+		return nil, err
+	}
+	if !sym.isFunction() {
+		return nil, nil
+	}
+	result, ok := ar.get(sym.result.name)
+	if !ok {
+		return nil, newErrRuntime(0, "function %v did not assign its result", sym.name)
+	}
+	return result, nil
+}
+
+// callVariable calls the routine held by a variable of a procedural type.
+func (i *interpreter) callVariable(name *token, variable node, args []node, switches switches) (interface{}, error) {
+	value, err := i.visit(variable)
+	if err != nil {
+		return nil, err
+	}
+	routine := value.(*routineValue)
+	if routine == nil {
+		return nil, newErrRuntime(errCodeAccessViolation, "call of NIL procedural variable %v", name.value)
+	}
+	return i.call(routine.symbol, routine.access, args, switches)
+}
+
+func (i *interpreter) VisitProcedureCallNode(n node) (interface{}, error) {
+	r := n.(*procedureCallNode)
+	if r.builtin != nil {
+		value, err := r.builtin.run(i, &builtinCall{name: r.name, args: r.args, argTypes: r.argTypes, switches: r.switches})
+		return value, locate(err, r.name)
+	}
+	if r.variable != nil {
+		value, err := i.callVariable(r.name, r.variable, r.args, r.switches)
+		return value, locate(err, r.name)
+	}
+	value, err := i.call(r.symbol, i.record(r.symbol.level), r.args, r.switches)
+	return value, locate(err, r.name)
+}
+
+func (i *interpreter) VisitFunctionCallNode(n node) (interface{}, error) {
+	r := n.(*functionCallNode)
+	if r.builtin != nil {
+		value, err := r.builtin.run(i, &builtinCall{name: r.name, args: r.args, argTypes: r.argTypes, switches: r.switches})
+		return value, locate(err, r.name)
+	}
+	if r.variable != nil {
+		value, err := i.callVariable(r.name, r.variable, r.args, r.switches)
+		return value, locate(err, r.name)
+	}
+	value, err := i.call(r.symbol, i.record(r.symbol.level), r.args, r.switches)
+	return value, locate(err, r.name)
+}
+
 func (i *interpreter) VisitVarNode(n node) (interface{}, error) {
 	r := n.(*varNode)
-	id := r.t.value.(string)
-	if value, ok := i.globalScope[id]; ok {
+	if r.constant != nil {
+		return r.constant.value, nil
+	}
+	if r.call != nil {
+		return i.visit(r.call)
+	}
+	if r.field != nil {
+		return i.visit(r.field)
+	}
+	if r.routine != nil {
+		return &routineValue{symbol: r.routine, access: i.record(r.routine.level)}, nil
+	}
+	if value, ok := i.readVariable(r.symbol); ok {
 		return value, nil
 	}
-	return nil, newErrUndefinedIdentifier(id)
+	return nil, newErrUndefinedIdentifier(r.symbol.name)
+}
+
+// arrayOffset evaluates the index of an indexed variable and returns the
+// offset of the element. Indexes are always checked, whatever the range check
+// switch says.
+func (i *interpreter) arrayOffset(r *indexNode) (int, error) {
+	index, err := i.visit(r.index)
+	if err != nil {
+		return 0, err
+	}
+	typ := r.typ.(*arrayType)
+	ord := ordinalValue(index)
+	if !typ.bounds.contains(ord) {
+		return 0, locate(newErrRuntime(errCodeRangeCheck, "index %v is out of bounds %v..%v",
+			ordinalString(typ.index, ord),
+			ordinalString(typ.index, typ.bounds.low),
+			ordinalString(typ.index, typ.bounds.high)), r.t)
+	}
+	return ord - typ.bounds.low, nil
+}
+
+// dynamicOffset evaluates the index of an element of a dynamic or open array
+// of the given length, which is also its offset.
+func (i *interpreter) dynamicOffset(r *indexNode, length int) (int, error) {
+	index, err := i.visit(r.index)
+	if err != nil {
+		return 0, err
+	}
+	// Big integers are clamped to the range of int, which is out of bounds.
+	ord := ordinalValue(index)
+	if ord < 0 || ord >= length {
+		return 0, locate(newErrRuntime(errCodeRangeCheck, "index %v is out of bounds 0..%d", index, length-1), r.t)
+	}
+	return ord, nil
+}
+
+// stringOffset evaluates the index of a character of s and returns its
+// offset. The index must be within the current length of s.
+func (i *interpreter) stringOffset(r *indexNode, s string) (int, error) {
+	index, err := i.visit(r.index)
+	if err != nil {
+		return 0, err
+	}
+	if index := ordinalValue(index); index < 1 || index > len(s) {
+		return 0, locate(newErrRuntime(errCodeRangeCheck, "index %d is out of bounds 1..%d", index, len(s)), r.t)
+	}
+	return ordinalValue(index) - 1, nil
+}
+
+func (i *interpreter) VisitIndexNode(n node) (interface{}, error) {
+	r := n.(*indexNode)
+	array, err := i.visit(r.array)
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := array.(string); ok {
+		offset, err := i.stringOffset(r, s)
+		if err != nil {
+			return nil, err
+		}
+		return s[offset], nil
+	}
+	if _, ok := r.typ.(*dynamicArrayType); ok {
+		elements := arrayElements(array)
+		offset, err := i.dynamicOffset(r, len(elements))
+		if err != nil {
+			return nil, err
+		}
+		return elements[offset], nil
+	}
+	offset, err := i.arrayOffset(r)
+	if err != nil {
+		return nil, err
+	}
+	return array.([]interface{})[offset], nil
+}
+
+func (i *interpreter) VisitFieldNode(n node) (interface{}, error) {
+	r := n.(*fieldNode)
+	record, err := i.visit(r.record)
+	if err != nil {
+		return nil, err
+	}
+	return record.([]interface{})[r.symbol.index], nil
+}
+
+func (i *interpreter) VisitDerefNode(n node) (interface{}, error) {
+	ref, err := i.reference(n)
+	if err != nil {
+		return nil, err
+	}
+	return ref.get(), nil
+}
+
+func (i *interpreter) VisitPointerTypeNode(n node) (interface{}, error) {
+	return nil, nil
+}
+
+func (i *interpreter) VisitProcedureTypeNode(n node) (interface{}, error) {
+	return nil, nil
+}
+
+func (i *interpreter) VisitArrayTypeNode(n node) (interface{}, error) {
+	return nil, nil
+}
+
+func (i *interpreter) VisitRecordTypeNode(n node) (interface{}, error) {
+	return nil, nil
 }
 
 func (i *interpreter) VisitTypeNode(n node) (interface{}, error) {
@@ -109,18 +565,217 @@ func (i *interpreter) VisitTypeNode(n node) (interface{}, error) {
 	return nil, nil
 }
 
+func (i *interpreter) VisitTypeDeclNode(n node) (interface{}, error) {
+	return nil, nil
+}
+
+func (i *interpreter) VisitEnumTypeNode(n node) (interface{}, error) {
+	return nil, nil
+}
+
+func (i *interpreter) VisitSubrangeTypeNode(n node) (interface{}, error) {
+	return nil, nil
+}
+
 func (i *interpreter) VisitAssignNode(n node) (interface{}, error) {
 	r := n.(*assignNode)
 	right, err := i.visit(r.right)
 	if err != nil {
 		return nil, err
 	}
-	i.globalScope[r.t.value.(string)] = right
+	if r.switches.rangeChecks {
+		if err = checkRange(r.typ, right); err != nil {
+			return nil, locate(err, r.t)
+		}
+	}
+	if typ, ok := r.typ.(*decimalType); ok {
+		if right, err = i.convertDecimal(typ, right, r.t); err != nil {
+			return nil, err
+		}
+	}
+	left, err := i.reference(r.left)
+	if err != nil {
+		return nil, err
+	}
+	left.set(copyValue(convertValue(r.typ, right)))
 	return nil, nil
 }
 
+func (i *interpreter) VisitIfNode(n node) (interface{}, error) {
+	r := n.(*ifNode)
+	condition, err := i.visitBoolean(r.condition)
+	if err != nil {
+		return nil, err
+	}
+	if condition {
+		return i.visit(r.thenNode)
+	}
+	if r.elseNode != nil {
+		return i.visit(r.elseNode)
+	}
+	return nil, nil
+}
+
+// VisitWithNode binds the records of a WITH statement to hidden variables
+// once, before the body is executed. Records that are variables are bound by
+// reference.
+func (i *interpreter) VisitWithNode(n node) (interface{}, error) {
+	r := n.(*withNode)
+	for index, record := range r.records {
+		sym := r.symbols[index]
+		if sym.byRef {
+			ref, err := i.reference(record)
+			if err != nil {
+				return nil, err
+			}
+			i.record(sym.level).set(sym.name, ref)
+			continue
+		}
+		value, err := i.visit(record)
+		if err != nil {
+			return nil, err
+		}
+		i.record(sym.level).set(sym.name, value)
+	}
+	return i.visit(r.body)
+}
+
+func (i *interpreter) VisitWhileNode(n node) (interface{}, error) {
+	r := n.(*whileNode)
+	for {
+		condition, err := i.visitBoolean(r.condition)
+		if err != nil {
+			return nil, err
+		}
+		if !condition {
+			return nil, nil
+		}
+		if _, err = i.visit(r.body); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (i *interpreter) VisitRepeatNode(n node) (interface{}, error) {
+	r := n.(*repeatNode)
+	for {
+		for _, child := range r.children {
+			if _, err := i.visit(child); err != nil {
+				return nil, err
+			}
+		}
+		condition, err := i.visitBoolean(r.condition)
+		if err != nil {
+			return nil, err
+		}
+		if condition {
+			return nil, nil
+		}
+	}
+}
+
+// VisitForNode evaluates the bounds once before the first iteration. As in
+// Turbo Pascal, the control variable keeps the final value after the loop, and
+// it is left untouched if the body is never executed. With range checks on,
+// both bounds must fit into the type of the control variable.
+func (i *interpreter) VisitForNode(n node) (interface{}, error) {
+	r := n.(*forNode)
+	start, err := i.visit(r.start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := i.visit(r.end)
+	if err != nil {
+		return nil, err
+	}
+
+	first, last := ordinalValue(start), ordinalValue(end)
+	step := 1
+	if r.downto {
+		step = -1
+	}
+	if !r.downto && first > last || r.downto && first < last {
+		return nil, nil
+	}
+	variable := r.variable.(*varNode)
+	if r.switches.rangeChecks {
+		for _, bound := range []interface{}{start, end} {
+			if err = checkRange(variable.symbol.typ, bound); err != nil {
+				return nil, locate(err, variable.t)
+			}
+		}
+	}
+	for ord := first; ; ord += step {
+		i.writeVariable(variable.symbol, ordinalToValue(variable.symbol.typ, ord))
+		if _, err = i.visit(r.body); err != nil {
+			return nil, err
+		}
+		if ord == last {
+			return nil, nil
+		}
+	}
+}
+
+func (i *interpreter) VisitCaseNode(n node) (interface{}, error) {
+	r := n.(*caseNode)
+	selector, err := i.visit(r.selector)
+	if err != nil {
+		return nil, err
+	}
+	ord := ordinalValue(selector)
+	for _, b := range r.branches {
+		branch := b.(*caseBranchNode)
+		for _, labelRange := range branch.ranges {
+			if labelRange.contains(ord) {
+				return i.visit(branch.body)
+			}
+		}
+	}
+	if r.elseNode == nil {
+		return nil, locate(newErrRuntime(0, "no case label matches %v", selector), r.t)
+	}
+	return i.visit(r.elseNode)
+}
+
+// visitBoolean visits an expression that must evaluate to a boolean.
+func (i *interpreter) visitBoolean(n node) (bool, error) {
+	value, err := i.visit(n)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, newErrTypeMismatch(tokenTypeBoolean, value)
+	}
+	return b, nil
+}
+
+// visitLogicalNode evaluates AND and OR. For booleans, the right operand is
+// only evaluated if the left one does not already decide the result. For
+// integers, they are bitwise operators.
+func (i *interpreter) visitLogicalNode(r *binaryNode) (interface{}, error) {
+	left, err := i.visit(r.left)
+	if err != nil {
+		return nil, err
+	}
+	if isIntegerValue(left) {
+		right, err := i.visit(r.right)
+		if err != nil {
+			return nil, err
+		}
+		return bitwise(r.t.tokenType, left, right), nil
+	}
+	if r.t.tokenType == tokenTypeAnd && !left.(bool) || r.t.tokenType == tokenTypeOr && left.(bool) {
+		return left, nil
+	}
+	return i.visitBoolean(r.right)
+}
+
 func (i *interpreter) VisitBinaryNode(n node) (interface{}, error) {
 	r := n.(*binaryNode)
+	if r.t.tokenType == tokenTypeAnd || r.t.tokenType == tokenTypeOr {
+		return i.visitLogicalNode(r)
+	}
 	left, err := i.visit(r.left)
 	if err != nil {
 		return nil, err
@@ -130,6 +785,90 @@ func (i *interpreter) VisitBinaryNode(n node) (interface{}, error) {
 		return nil, err
 	}
 
+	if typ, ok := r.typ.(*integerType); ok {
+		if typ.unbounded() {
+			return visitBigOperation(r, left, right)
+		}
+		return visitIntegerOperation(r, typ, left.(int), right.(int))
+	}
+	if typ, ok := r.typ.(*realType); ok {
+		return i.visitRealOperation(r, typ, left, right)
+	}
+	if typ, ok := r.typ.(*decimalType); ok {
+		return i.visitDecimalOperation(r, typ, left, right)
+	}
+	if isComplex(r.typ) {
+		return i.visitComplexOperation(r, left, right)
+	}
+	if leftSet, ok := left.(set); ok && isRelationalOperator(r.t.tokenType) {
+		return compareSets(r.t.tokenType, leftSet, right.(set)), nil
+	}
+	// NaN is unordered: it is neither equal to, less than nor greater than
+	// any number, itself included.
+	if isRelationalOperator(r.t.tokenType) && (isNaN(left) || isNaN(right)) {
+		return r.t.tokenType == tokenTypeNotEqual, nil
+	}
+	switch r.t.tokenType {
+	case tokenTypeIn:
+		return right.(set).contains(ordinalValue(left)), nil
+	case tokenTypeEqual:
+		return compare(left, right) == 0, nil
+	case tokenTypeNotEqual:
+		return compare(left, right) != 0, nil
+	case tokenTypeLess:
+		return compare(left, right) < 0, nil
+	case tokenTypeLessEqual:
+		return compare(left, right) <= 0, nil
+	case tokenTypeGreater:
+		return compare(left, right) > 0, nil
+	case tokenTypeGreaterEqual:
+		return compare(left, right) >= 0, nil
+	case tokenTypePlus:
+		return add(left, right), nil
+	case tokenTypeMinus:
+		return minus(left, right), nil
+	case tokenTypeMul:
+		return mul(left, right), nil
+	case tokenTypeXor:
+		return left.(bool) != right.(bool), nil
+	}
+	return nil, nil
+}
+
+// visitIntegerOperation applies an arithmetic or bitwise operator to
+// integers. The result wraps around to typ, the type the operation is
+// evaluated in, unless overflow checks are on.
+func visitIntegerOperation(r *binaryNode, typ *integerType, left, right int) (interface{}, error) {
+	var result int
+	var overflow bool
+	switch r.t.tokenType {
+	case tokenTypePlus, tokenTypeMinus, tokenTypeMul:
+		result, overflow = arithmeticInt(r.t.tokenType, left, right)
+	case tokenTypeDivInteger:
+		if right == 0 {
+			return nil, locate(newErrRuntime(errCodeDivisionByZero, "division by zero"), r.t)
+		}
+		result, overflow = arithmeticInt(r.t.tokenType, left, right)
+	case tokenTypeMod:
+		if right <= 0 {
+			if right == 0 {
+				return nil, locate(newErrRuntime(errCodeDivisionByZero, "division by zero"), r.t)
+			}
+			return nil, locate(newErrRuntime(0, "MOD by a negative number: %d", right), r.t)
+		}
+		return modInt(left, right), nil
+	case tokenTypeShl, tokenTypeShr:
+		return shift(r.t.tokenType, left, right, typ), nil
+	default:
+		return bitwise(r.t.tokenType, left, right), nil
+	}
+	return checkOverflow(typ, r.switches, r.t, result, overflow)
+}
+
+// visitBigOperation applies an arithmetic or bitwise operator to integers
+// of a type without bounds. The result is a big integer if it does not fit
+// into an int, so it never overflows.
+func visitBigOperation(r *binaryNode, left, right interface{}) (interface{}, error) {
 	switch r.t.tokenType {
 	case tokenTypePlus:
 		return add(left, right), nil
@@ -137,16 +876,209 @@ func (i *interpreter) VisitBinaryNode(n node) (interface{}, error) {
 		return minus(left, right), nil
 	case tokenTypeMul:
 		return mul(left, right), nil
-	case tokenTypeDivReal:
-		return divReal(left, right), nil
 	case tokenTypeDivInteger:
+		if sign(right) == 0 {
+			return nil, locate(newErrRuntime(errCodeDivisionByZero, "division by zero"), r.t)
+		}
 		return divInt(left, right), nil
+	case tokenTypeMod:
+		if sign(right) <= 0 {
+			if sign(right) == 0 {
+				return nil, locate(newErrRuntime(errCodeDivisionByZero, "division by zero"), r.t)
+			}
+			return nil, locate(newErrRuntime(0, "MOD by a negative number: %v", right), r.t)
+		}
+		return modInt(left, right), nil
+	case tokenTypeShl, tokenTypeShr:
+		count, ok := right.(int)
+		if !ok || count < 0 {
+			return nil, locate(newErrRuntime(errCodeRangeCheck, "shift count %v is out of range", right), r.t)
+		}
+		return shiftBig(r.t.tokenType, left, count), nil
+	}
+	return bitwise(r.t.tokenType, left, right), nil
+}
+
+// visitRealOperation applies an arithmetic operator to numbers of which at
+// least one is real, or to integers for /. The result is rounded to typ,
+// and floating-point exceptions that are not masked are runtime errors.
+func (i *interpreter) visitRealOperation(r *binaryNode, typ *realType, left, right interface{}) (interface{}, error) {
+	mask := i.options.fpuMask
+	x, y := toFloat(left), toFloat(right)
+	var result float64
+	switch r.t.tokenType {
+	case tokenTypePlus:
+		result = add(left, right).(float64)
+	case tokenTypeMinus:
+		result = minus(left, right).(float64)
+	case tokenTypeMul:
+		result = mul(left, right).(float64)
+	default:
+		if y == 0 && isFinite(x) {
+			exception := fpuDivideByZero
+			if x == 0 {
+				exception = fpuInvalid
+			}
+			if err := mask.raise(exception); err != nil {
+				return nil, locate(err, r.t)
+			}
+		}
+		result = divReal(left, right).(float64)
+	}
+	result = typ.round(result)
+	// Operations on infinities and NaN, which come from masked exceptions,
+	// raise no further exceptions.
+	if !isFinite(x) || !isFinite(y) || r.t.tokenType == tokenTypeDivReal && y == 0 {
+		return result, nil
+	}
+	value, err := checkFloat(mask, result)
+	return value, locate(err, r.t)
+}
+
+// visitComplexOperation applies an arithmetic operator to numbers of which
+// at least one is complex. Floating-point exceptions are raised as for
+// reals, for each part of the result.
+func (i *interpreter) visitComplexOperation(r *binaryNode, left, right interface{}) (interface{}, error) {
+	mask := i.options.fpuMask
+	x, y := toComplex(left), toComplex(right)
+	var result complex128
+	switch r.t.tokenType {
+	case tokenTypePlus:
+		result = add(left, right).(complex128)
+	case tokenTypeMinus:
+		result = minus(left, right).(complex128)
+	case tokenTypeMul:
+		result = mul(left, right).(complex128)
+	default:
+		if y == 0 && isFiniteComplex(x) {
+			exception := fpuDivideByZero
+			if x == 0 {
+				exception = fpuInvalid
+			}
+			if err := mask.raise(exception); err != nil {
+				return nil, locate(err, r.t)
+			}
+		}
+		result = divReal(left, right).(complex128)
+	}
+	if !isFiniteComplex(x) || !isFiniteComplex(y) || r.t.tokenType == tokenTypeDivReal && y == 0 {
+		return result, nil
+	}
+	value, err := checkComplex(mask, result)
+	return value, locate(err, r.t)
+}
+
+// visitDecimalOperation applies an arithmetic operator to fixed-point
+// numbers and integers. The result is exact, except that quotients are
+// rounded, and CURRENCY results are rounded to four decimals, halves to even.
+// Division by zero is runtime error 200, and infinite or NaN operands and
+// results out of the range of CURRENCY are invalid operations.
+func (i *interpreter) visitDecimalOperation(r *binaryNode, typ *decimalType, left, right interface{}) (interface{}, error) {
+	for _, operand := range []interface{}{left, right} {
+		if x, ok := operand.(float64); ok && !isFinite(x) {
+			return i.convertDecimal(typ, x, r.t)
+		}
 	}
+	var result interface{}
+	switch r.t.tokenType {
+	case tokenTypePlus:
+		result = add(left, right)
+	case tokenTypeMinus:
+		result = minus(left, right)
+	case tokenTypeMul:
+		result = mul(left, right)
+	default:
+		if sign(right) == 0 {
+			return nil, locate(newErrRuntime(errCodeDivisionByZero, "division by zero"), r.t)
+		}
+		if typ.currency {
+			result = toDecimal(left).quo(toDecimal(right), currencyDecimals)
+		} else {
+			result = divReal(left, right)
+		}
+	}
+	return i.convertDecimal(typ, result, r.t)
+}
+
+// convertDecimal converts a number to a fixed-point type. A number out of
+// the range of the type is an invalid operation, which gives the value
+// convert gives if it is masked.
+func (i *interpreter) convertDecimal(typ *decimalType, v interface{}, t *token) (interface{}, error) {
+	value, ok := typ.convert(v)
+	if !ok {
+		if err := i.options.fpuMask.raise(fpuInvalid); err != nil {
+			return nil, locate(err, t)
+		}
+	}
+	return value, nil
+}
+
+// checkOverflow wraps the result of an arithmetic operation around to typ.
+// overflow is true if the result has already wrapped around to 64 bits.
+// With overflow checks on, a result that does not fit is a runtime error.
+func checkOverflow(typ *integerType, s switches, t *token, result int, overflow bool) (interface{}, error) {
+	wrapped := typ.wrap(result)
+	if s.overflowChecks && (overflow || wrapped != result) {
+		return nil, locate(newErrRuntime(errCodeOverflow, "arithmetic overflow: result is out of range for %v", typ), t)
+	}
+	return wrapped, nil
+}
+
+// VisitSetNode builds a set. Elements outside of 0..maxSetOrdinal cause a
+// range check error.
+func (i *interpreter) VisitSetNode(n node) (interface{}, error) {
+	r := n.(*setNode)
+	var s set
+	for _, element := range r.elements {
+		low, high := element, element
+		if rangeNode, ok := element.(*rangeNode); ok {
+			low, high = rangeNode.low, rangeNode.high
+		}
+		lowValue, err := i.visit(low)
+		if err != nil {
+			return nil, err
+		}
+		highValue, err := i.visit(high)
+		if err != nil {
+			return nil, err
+		}
+		for ord := ordinalValue(lowValue); ord <= ordinalValue(highValue); ord++ {
+			if ord < 0 || ord > maxSetOrdinal {
+				return nil, locate(newErrRuntime(errCodeRangeCheck, "set element %d is out of range 0..%d", ord, maxSetOrdinal), r.t)
+			}
+			s.include(ord)
+		}
+	}
+	return s, nil
+}
+
+func (i *interpreter) VisitStringTypeNode(n node) (interface{}, error) {
+	return nil, nil
+}
+
+func (i *interpreter) VisitFileTypeNode(n node) (interface{}, error) {
+	return nil, nil
+}
+
+func (i *interpreter) VisitSetTypeNode(n node) (interface{}, error) {
 	return nil, nil
 }
 
 func (i *interpreter) VisitUnaryNode(n node) (interface{}, error) {
 	r := n.(*unaryNode)
+	if r.t.tokenType == tokenTypeNot {
+		childValue, err := i.visit(r.child)
+		if err != nil {
+			return nil, err
+		}
+		switch v := childValue.(type) {
+		case int:
+			return r.typ.(*integerType).wrap(^v), nil
+		case *big.Int:
+			return new(big.Int).Not(v), nil
+		}
+		return !childValue.(bool), nil
+	}
 	childValue, err := i.visit(r.child)
 	if err != nil {
 		return nil, err
@@ -155,6 +1087,16 @@ func (i *interpreter) VisitUnaryNode(n node) (interface{}, error) {
 	if r.t.tokenType == tokenTypeMinus {
 		switch v := childValue.(type) {
 		case int:
+			if r.typ.(*integerType).unbounded() {
+				return minus(0, v), nil
+			}
+			result, overflow := arithmeticInt(tokenTypeMinus, 0, v)
+			return checkOverflow(r.typ.(*integerType), r.switches, r.t, result, overflow)
+		case *big.Int:
+			return normalizeInt(new(big.Int).Neg(v)), nil
+		case currency, decimal:
+			return i.convertDecimal(r.typ.(*decimalType), toDecimal(v).neg(), r.t)
+		case complex128:
 			return -v, nil
 		case float64:
 			return -v, nil
@@ -165,8 +1107,22 @@ func (i *interpreter) VisitUnaryNode(n node) (interface{}, error) {
 	}
 }
 
+func (i *interpreter) VisitAddressNode(n node) (interface{}, error) {
+	r := n.(*addressNode)
+	return i.visit(r.routine)
+}
+
 func (i *interpreter) VisitValueNode(n node) (interface{}, error) {
 	r := n.(*valueNode)
+	if r.t.tokenType == tokenTypeNil {
+		return (*heapCell)(nil), nil
+	}
+	if s, ok := r.t.value.(string); ok && r.t.tokenType == tokenTypeStringConst && len(s) == 1 {
+		return s[0], nil
+	}
+	if d, ok := r.t.value.(decimal); ok && !r.exact {
+		return d.float(), nil
+	}
 	return r.t.value, nil
 }
 
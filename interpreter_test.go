@@ -1,6 +1,13 @@
 package go_pascal
 
-import "testing"
+import (
+	"io/fs"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+)
 
 func TestInterpreter(t *testing.T) {
 	program := `
@@ -28,10 +35,2950 @@ END.
 		"_num": 5,
 	}
 	for id, value := range expect {
-		if got, ok := i.globalScope[id]; !ok {
+		if got, ok := i.globalScope.get(id); !ok {
 			t.Fatalf("%v is expected to be in the symbol table", id)
 		} else if got != value {
 			t.Fatalf("expected to get %v for %v; got %v", value, id, got)
 		}
 	}
 }
+
+func TestInterpreterIf(t *testing.T) {
+	program := `
+PROGRAM conditions;
+VAR
+	flag : BOOLEAN;
+BEGIN
+	a := 1;
+	b := 2;
+	flag := NOT (a > b) AND (b >= 2.0);
+	less := a < b;
+	{AND binds tighter than OR}
+	precedence := TRUE OR FALSE AND FALSE;
+	notEqual := a <> b;
+	IF flag THEN c := 1 ELSE c := 2;
+	IF a = b THEN d := 1 ELSE IF a < b THEN d := 2 ELSE d := 3;
+	{the ELSE belongs to the inner IF}
+	e := 0;
+	IF a > b THEN IF TRUE THEN e := 1 ELSE e := 2;
+	f := 0;
+	IF a < b THEN IF FALSE THEN f := 1 ELSE f := 2;
+	{b DIV 0 would fail if the right operand were evaluated}
+	shortCircuit := (a > b) AND (b DIV 0 = 1)
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]interface{}{
+		"flag":         true,
+		"less":         true,
+		"precedence":   true,
+		"notequal":     true,
+		"c":            1,
+		"d":            2,
+		"e":            0,
+		"f":            2,
+		"shortcircuit": false,
+	}
+	checkGlobalScope(t, i, expect)
+}
+
+func TestInterpreterIfErrors(t *testing.T) {
+	tests := []string{
+		`PROGRAM test; BEGIN IF 1 THEN a := 1 END.`,
+		`PROGRAM test; BEGIN a := NOT 1.5 END.`,
+		`PROGRAM test; BEGIN IF 1 < 2 < 3 THEN a := 1 END.`,
+	}
+	for _, program := range tests {
+		if err := newInterpreter(program).walk(); err == nil {
+			t.Fatalf("expected an error for %q", program)
+		}
+	}
+}
+
+func TestInterpreterConditionErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		err     string
+	}{{
+		program: `PROGRAM test; BEGIN IF 'a' THEN END.`,
+		err:     "1:24: condition must be BOOLEAN; got CHAR",
+	}, {
+		program: `PROGRAM test; VAR x : REAL; BEGIN WHILE x + 1 DO END.`,
+		err:     "1:43: condition must be BOOLEAN; got REAL",
+	}, {
+		program: `PROGRAM test; VAR s : STRING; BEGIN REPEAT UNTIL s END.`,
+		err:     "1:50: condition must be BOOLEAN; got STRING",
+	}}
+	for _, test := range tests {
+		err := newInterpreter(test.program).walk()
+		if _, ok := err.(*errSemantic); !ok || err.Error() != test.err {
+			t.Fatalf("expected %q for %q; got %v", test.err, test.program, err)
+		}
+	}
+}
+
+func TestInterpreterLoops(t *testing.T) {
+	tests := []struct {
+		program string
+		expect  map[string]interface{}
+	}{{
+		program: `
+PROGRAM zeroWhile;
+BEGIN
+	n := 0;
+	count := 0;
+	WHILE n > 0 DO
+		count := count + 1
+END.
+`,
+		expect: map[string]interface{}{"n": 0, "count": 0},
+	}, {
+		program: `
+PROGRAM sumWhile;
+BEGIN
+	n := 100;
+	sum := 0;
+	WHILE n > 0 DO
+	BEGIN
+		sum := sum + n;
+		n := n - 1
+	END
+END.
+`,
+		expect: map[string]interface{}{"n": 0, "sum": 5050},
+	}, {
+		program: `
+PROGRAM onceRepeat;
+BEGIN
+	count := 0;
+	REPEAT
+		count := count + 1
+	UNTIL TRUE
+END.
+`,
+		expect: map[string]interface{}{"count": 1},
+	}, {
+		program: `
+PROGRAM gcdRepeat;
+BEGIN
+	a := 1071;
+	b := 462;
+	steps := 0;
+	REPEAT
+		r := a - a DIV b * b;
+		a := b;
+		b := r;
+		steps := steps + 1;
+	UNTIL b = 0
+END.
+`,
+		expect: map[string]interface{}{"a": 21, "b": 0, "steps": 3},
+	}, {
+		program: `
+PROGRAM nestedLoops;
+BEGIN
+	i := 0;
+	total := 0;
+	WHILE i < 10 DO
+	BEGIN
+		j := 0;
+		REPEAT
+			total := total + 1;
+			j := j + 1
+		UNTIL j >= i;
+		i := i + 1
+	END
+END.
+`,
+		expect: map[string]interface{}{"i": 10, "total": 46},
+	}}
+
+	for _, test := range tests {
+		i := newInterpreter(test.program)
+		if err := i.walk(); err != nil {
+			t.Fatal(err)
+		}
+		checkGlobalScope(t, i, test.expect)
+	}
+}
+
+func checkGlobalScope(t *testing.T, i *interpreter, expect map[string]interface{}) {
+	t.Helper()
+	for id, value := range expect {
+		if got, ok := i.globalScope.get(id); !ok {
+			t.Fatalf("%v is expected to be in the symbol table", id)
+		} else if got != value {
+			t.Fatalf("expected to get %v for %v; got %v", value, id, got)
+		}
+	}
+}
+
+func TestInterpreterFor(t *testing.T) {
+	tests := []struct {
+		program string
+		expect  map[string]interface{}
+	}{{
+		program: `
+PROGRAM sum;
+VAR
+	i, total : INTEGER;
+BEGIN
+	total := 0;
+	FOR i := 1 TO 10 DO
+		total := total + i
+END.
+`,
+		expect: map[string]interface{}{"i": 10, "total": 55},
+	}, {
+		program: `
+PROGRAM countdown;
+VAR
+	i, n, last : INTEGER;
+BEGIN
+	n := 3;
+	{the bounds are evaluated only once}
+	FOR i := n DOWNTO 1 DO
+	BEGIN
+		n := n + 1;
+		last := i
+	END
+END.
+`,
+		expect: map[string]interface{}{"i": 1, "n": 6, "last": 1},
+	}, {
+		program: `
+PROGRAM empty;
+VAR
+	i, count : INTEGER;
+BEGIN
+	i := 42;
+	count := 0;
+	FOR i := 5 TO 1 DO
+		count := count + 1
+END.
+`,
+		expect: map[string]interface{}{"i": 42, "count": 0},
+	}, {
+		program: `
+PROGRAM nested;
+VAR
+	i, j, count : INTEGER;
+	b : BOOLEAN;
+BEGIN
+	count := 0;
+	FOR i := 1 TO 4 DO
+		FOR j := i TO 4 DO
+			count := count + 1;
+	FOR b := FALSE TO TRUE DO
+		count := count + 100
+END.
+`,
+		expect: map[string]interface{}{"i": 4, "j": 4, "b": true, "count": 210},
+	}}
+
+	for _, test := range tests {
+		i := newInterpreter(test.program)
+		if err := i.walk(); err != nil {
+			t.Fatal(err)
+		}
+		checkGlobalScope(t, i, test.expect)
+	}
+}
+
+func TestInterpreterForErrors(t *testing.T) {
+	tests := []string{
+		`PROGRAM test; VAR x : REAL; BEGIN FOR x := 1 TO 2 DO END.`,
+		`PROGRAM test; VAR i : INTEGER; BEGIN FOR i := 1 TO 2 DO i := 5 END.`,
+		`PROGRAM test; VAR i : INTEGER; BEGIN FOR i := 1 TO 2 DO FOR i := 1 TO 2 DO END.`,
+		`PROGRAM test; VAR i : INTEGER; BEGIN FOR i := 1 TO 2.5 DO END.`,
+		`PROGRAM test; VAR i : INTEGER; BEGIN FOR i := 1 TO 2 DO BEGIN IF i > 1 THEN i := 0 END END.`,
+	}
+	for _, program := range tests {
+		if err := newInterpreter(program).walk(); err == nil {
+			t.Fatalf("expected an error for %q", program)
+		}
+	}
+}
+
+func TestInterpreterCase(t *testing.T) {
+	program := `
+PROGRAM classify;
+VAR
+	i, small, middle, large, other, flags : INTEGER;
+	b : BOOLEAN;
+BEGIN
+	small := 0; middle := 0; large := 0; other := 0; flags := 0;
+	FOR i := -2 TO 12 DO
+		CASE i OF
+			1, 2: small := small + 1;
+			3..5, 7: middle := middle + 1;
+			-2..-1, 10..11: large := large + 1;
+		ELSE
+			other := other + 1;
+			other := other + 1
+		END;
+	b := 2 > 1;
+	CASE b OF
+		TRUE: flags := 1;
+		FALSE: flags := 2
+	END
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"small":  2,
+		"middle": 4,
+		"large":  4,
+		"other":  10,
+		"flags":  1,
+	})
+}
+
+func TestInterpreterCaseErrors(t *testing.T) {
+	tests := []struct {
+		program   string
+		isRuntime bool
+	}{{
+		program: `PROGRAM test; BEGIN CASE 1 OF 1: ; 2, 1: END END.`,
+	}, {
+		program: `PROGRAM test; BEGIN CASE 1 OF 1..5: ; 5..7: END END.`,
+	}, {
+		program: `PROGRAM test; BEGIN CASE 1 OF 5..1: END END.`,
+	}, {
+		program: `PROGRAM test; BEGIN CASE 1.5 OF 1: END END.`,
+	}, {
+		program: `PROGRAM test; BEGIN CASE 1 OF TRUE: END END.`,
+	}, {
+		program:   `PROGRAM test; BEGIN CASE 3 OF 1, 2: a := 1 END END.`,
+		isRuntime: true,
+	}}
+	for _, test := range tests {
+		err := newInterpreter(test.program).walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		if _, ok := err.(*errRuntime); ok != test.isRuntime {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterProcedures(t *testing.T) {
+	program := `
+PROGRAM procedures;
+VAR
+	x, result, a, b, counter, unchanged : INTEGER;
+
+PROCEDURE outer(n : INTEGER);
+VAR
+	x : INTEGER;
+
+	PROCEDURE inner;
+	BEGIN
+		{x is the local variable of outer, not the global one}
+		x := x + n
+	END;
+
+	PROCEDURE repeatInner(k : INTEGER);
+	BEGIN
+		IF k > 0 THEN
+		BEGIN
+			inner;
+			repeatInner(k - 1)
+		END
+	END;
+
+BEGIN
+	x := 100;
+	repeatInner(3);
+	result := x
+END;
+
+PROCEDURE swap(VAR a, b : INTEGER);
+VAR
+	t : INTEGER;
+BEGIN
+	t := a;
+	a := b;
+	b := t
+END;
+
+PROCEDURE increment(VAR v : INTEGER);
+BEGIN
+	v := v + 1
+END;
+
+PROCEDURE incrementTwice(VAR v : INTEGER);
+BEGIN
+	increment(v);
+	increment(v)
+END;
+
+PROCEDURE change(v : INTEGER);
+BEGIN
+	v := v * 10
+END;
+
+BEGIN
+	x := 1;
+	outer(2);
+	a := 5;
+	b := 7;
+	swap(a, b);
+	counter := 0;
+	incrementTwice(counter);
+	unchanged := 3;
+	change(unchanged)
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"x":         1,
+		"result":    106,
+		"a":         7,
+		"b":         5,
+		"counter":   2,
+		"unchanged": 3,
+	})
+}
+
+func TestInterpreterProcedureErrors(t *testing.T) {
+	tests := []string{
+		`PROGRAM test; PROCEDURE p(a : INTEGER); BEGIN END; BEGIN p END.`,
+		`PROGRAM test; PROCEDURE p(a : INTEGER); BEGIN END; BEGIN p(TRUE) END.`,
+		`PROGRAM test; PROCEDURE p(VAR a : INTEGER); BEGIN END; BEGIN p(1 + 2) END.`,
+		`PROGRAM test; VAR r : REAL; PROCEDURE p(VAR a : INTEGER); BEGIN END; BEGIN p(r) END.`,
+		`PROGRAM test; PROCEDURE p; VAR a : INTEGER; BEGIN END; BEGIN a := 1 + a END.`,
+		`PROGRAM test; PROCEDURE p(a, a : INTEGER); BEGIN END; BEGIN END.`,
+		`PROGRAM test; PROCEDURE p; BEGIN END; BEGIN p := 1 END.`,
+		`PROGRAM test; VAR i : INTEGER; PROCEDURE p(VAR a : INTEGER); BEGIN END; BEGIN FOR i := 1 TO 2 DO p(i) END.`,
+		`PROGRAM test; BEGIN q END.`,
+		`PROGRAM test; VAR i : INTEGER; PROCEDURE p; BEGIN FOR i := 1 TO 2 DO END; BEGIN END.`,
+	}
+	for _, program := range tests {
+		if err := newInterpreter(program).walk(); err == nil {
+			t.Fatalf("expected an error for %q", program)
+		}
+	}
+}
+
+func TestInterpreterFunctions(t *testing.T) {
+	program := `
+PROGRAM functions;
+VAR
+	f : LONGINT;
+	a, even, odd, zero : INTEGER;
+
+FUNCTION factorial(n : INTEGER) : LONGINT;
+BEGIN
+	IF n <= 1 THEN
+		factorial := 1
+	ELSE
+		factorial := n * factorial(n - 1)
+END;
+
+FUNCTION ackermann(m, n : INTEGER) : INTEGER;
+BEGIN
+	IF m = 0 THEN
+		ackermann := n + 1
+	ELSE IF n = 0 THEN
+		ackermann := ackermann(m - 1, 1)
+	ELSE
+		ackermann := ackermann(m - 1, ackermann(m, n - 1))
+END;
+
+FUNCTION isOdd(n : INTEGER) : BOOLEAN; FORWARD;
+
+FUNCTION isEven(n : INTEGER) : BOOLEAN;
+BEGIN
+	IF n = 0 THEN isEven := TRUE ELSE isEven := isOdd(n - 1)
+END;
+
+FUNCTION isOdd;
+BEGIN
+	IF n = 0 THEN isOdd := FALSE ELSE isOdd := isEven(n - 1)
+END;
+
+FUNCTION answer : INTEGER;
+	PROCEDURE setAnswer;
+	BEGIN
+		answer := 0
+	END;
+BEGIN
+	setAnswer
+END;
+
+BEGIN
+	f := factorial(10);
+	a := ackermann(2, 3);
+	even := 0;
+	odd := 0;
+	FOR zero := 0 TO 9 DO
+		IF isEven(zero) AND NOT isOdd(zero) THEN even := even + 1 ELSE odd := odd + 1;
+	zero := answer + answer
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"f":    3628800,
+		"a":    9,
+		"even": 5,
+		"odd":  5,
+		"zero": 0,
+	})
+}
+
+func TestInterpreterFunctionResult(t *testing.T) {
+	program := `
+PROGRAM results;
+VAR
+	x : INTEGER;
+
+FUNCTION square(n : INTEGER) : INTEGER;
+BEGIN
+	Result := n;
+	Result := Result * n
+END;
+
+FUNCTION twice(n : INTEGER) : INTEGER;
+BEGIN
+	twice := 2 * n
+END;
+
+BEGIN
+	x := square(7) + twice(1)
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err == nil {
+		t.Fatal("Result is expected to be undefined in the default mode")
+	}
+
+	i = newInterpreter(program)
+	i.options.dialect = dialectDelphi
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{"x": 51})
+
+	i = newInterpreter("{$MODE OBJFPC}" + program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{"x": 51})
+}
+
+func TestInterpreterFunctionErrors(t *testing.T) {
+	tests := []string{
+		`PROGRAM test; FUNCTION f : INTEGER; BEGIN f := 1 END; BEGIN f := 2 END.`,
+		`PROGRAM test; FUNCTION f : INTEGER; FORWARD; BEGIN END.`,
+		`PROGRAM test; FUNCTION f(a : INTEGER) : INTEGER; FORWARD; FUNCTION f(b : INTEGER) : INTEGER; BEGIN f := b END; BEGIN END.`,
+		`PROGRAM test; FUNCTION f : INTEGER; BEGIN f := TRUE END; BEGIN END.`,
+		`PROGRAM test; FUNCTION f; BEGIN END; BEGIN END.`,
+		`PROGRAM test; FUNCTION f : INTEGER; BEGIN f := 1 END; BEGIN f END.`,
+		`PROGRAM test; PROCEDURE p; BEGIN END; BEGIN a := p END.`,
+		`PROGRAM test; FUNCTION f : INTEGER; BEGIN END; BEGIN a := f END.`,
+		`PROGRAM test; FUNCTION f(n : INTEGER) : INTEGER; BEGIN f := f(n + 1) END; BEGIN a := f(1) END.`,
+	}
+	for _, program := range tests {
+		if err := newInterpreter(program).walk(); err == nil {
+			t.Fatalf("expected an error for %q", program)
+		}
+	}
+}
+
+func TestInterpreterProceduralTypes(t *testing.T) {
+	program := `
+PROGRAM sorting;
+TYPE
+	TCompare = FUNCTION(a, b : INTEGER) : INTEGER;
+	TNumbers = ARRAY [1..5] OF INTEGER;
+	TButton = RECORD
+		clicks : INTEGER;
+		OnClick : PROCEDURE(VAR x : INTEGER)
+	END;
+VAR
+	numbers, squares : TNumbers;
+	cmp : TCompare;
+	action : PROCEDURE(VAR x : INTEGER);
+	button : TButton;
+	ops : ARRAY [1..2] OF TCompare;
+	first, last, fourth, total, clicks, difference : INTEGER;
+	assigned, same : BOOLEAN;
+
+FUNCTION Ascending(a, b : INTEGER) : INTEGER;
+BEGIN
+	Ascending := a - b
+END;
+
+FUNCTION Descending(x, y : INTEGER) : INTEGER;
+BEGIN
+	Descending := y - x
+END;
+
+PROCEDURE Double(VAR x : INTEGER);
+BEGIN
+	x := 2 * x
+END;
+
+PROCEDURE Sort(VAR a : TNumbers; compare : TCompare);
+VAR
+	i, j, t : INTEGER;
+BEGIN
+	FOR i := 1 TO 4 DO
+		FOR j := i + 1 TO 5 DO
+			IF compare(a[i], a[j]) > 0 THEN
+			BEGIN
+				t := a[i]; a[i] := a[j]; a[j] := t
+			END
+END;
+
+PROCEDURE Map(VAR a : TNumbers; FUNCTION f(x : INTEGER) : INTEGER);
+VAR
+	i : INTEGER;
+BEGIN
+	FOR i := 1 TO 5 DO
+		a[i] := f(a[i])
+END;
+
+PROCEDURE Sum(a : TNumbers);
+	PROCEDURE Add(VAR x : INTEGER);
+	BEGIN
+		total := total + x
+	END;
+VAR
+	i : INTEGER;
+	p : PROCEDURE(VAR x : INTEGER);
+BEGIN
+	p := Add;
+	FOR i := 1 TO 5 DO
+		p(a[i])
+END;
+
+FUNCTION Square(x : INTEGER) : INTEGER;
+BEGIN
+	Square := x * x
+END;
+
+BEGIN
+	numbers[1] := 3; numbers[2] := 1; numbers[3] := 5; numbers[4] := 2; numbers[5] := 4;
+	assigned := cmp <> NIL;
+	cmp := Descending;
+	Sort(numbers, cmp);
+	first := numbers[1];
+	Sort(numbers, @Ascending);
+	last := numbers[5];
+	same := cmp = @Descending;
+	squares := numbers;
+	Map(squares, Square);
+	fourth := squares[4];
+	action := Double;
+	action(last);
+	total := 0;
+	Sum(numbers);
+	button.clicks := 3;
+	button.OnClick := Double;
+	button.OnClick(button.clicks);
+	clicks := button.clicks;
+	ops[1] := Ascending;
+	ops[2] := Descending;
+	difference := ops[2](1, 4) - ops[1](1, 4)
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"first":      5,
+		"last":       10,
+		"fourth":     16,
+		"total":      15,
+		"clicks":     6,
+		"difference": 6,
+		"assigned":   false,
+		"same":       true,
+	})
+}
+
+func TestInterpreterProceduralTypeErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		code    int
+	}{{
+		program: `PROGRAM test; TYPE T = FUNCTION(a, b : INTEGER) : INTEGER; VAR f : T;
+FUNCTION Neg(a : INTEGER) : INTEGER; BEGIN Neg := -a END;
+BEGIN f := Neg END.`,
+	}, {
+		program: `PROGRAM test; TYPE T = FUNCTION(a : INTEGER) : INTEGER; VAR f : T;
+FUNCTION Half(a : REAL) : REAL; BEGIN Half := a / 2 END;
+BEGIN f := Half END.`,
+	}, {
+		program: `PROGRAM test; VAR p : PROCEDURE(VAR a : INTEGER);
+PROCEDURE Inc(a : INTEGER); BEGIN END;
+BEGIN p := Inc END.`,
+	}, {
+		program: `PROGRAM test; VAR p : PROCEDURE; f : FUNCTION : INTEGER;
+BEGIN f := p END.`,
+	}, {
+		program: `PROGRAM test; VAR p : PROCEDURE(a : INTEGER);
+BEGIN p(1, 2) END.`,
+	}, {
+		program: `PROGRAM test; VAR f : FUNCTION(a : INTEGER) : INTEGER;
+BEGIN f(1) END.`,
+	}, {
+		program: `PROGRAM test; VAR f : FUNCTION(x : REAL) : REAL;
+BEGIN f := Sqrt END.`,
+	}, {
+		program: `PROGRAM test; VAR n : INTEGER; BEGIN n := @n END.`,
+	}, {
+		program: `PROGRAM test; VAR n : INTEGER; BEGIN n(1) END.`,
+	}, {
+		program: `PROGRAM test; VAR r : RECORD n : INTEGER END; BEGIN r.n(1) END.`,
+	}, {
+		program: `PROGRAM test; VAR ops : ARRAY [1..2] OF PROCEDURE(a : INTEGER);
+BEGIN ops[1](1, 2) END.`,
+	}, {
+		program: `PROGRAM test; VAR ops : ARRAY [1..2] OF FUNCTION : INTEGER; n : INTEGER;
+BEGIN n := ops[2]() END.`,
+		code: errCodeAccessViolation,
+	}, {
+		program: `PROGRAM test; VAR f : FUNCTION(a : INTEGER) : INTEGER; n : INTEGER;
+BEGIN n := f(1) END.`,
+		code: errCodeAccessViolation,
+	}}
+	for _, test := range tests {
+		err := newInterpreter(test.program).walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		if runtimeErr, ok := err.(*errRuntime); ok != (test.code != 0) || ok && runtimeErr.code != test.code {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterConstants(t *testing.T) {
+	program := `
+PROGRAM constants;
+CONST
+	size = 10;
+	half = size DIV 2;
+	negative = -half;
+	ratio = size / 4;
+	big = (size > half) AND NOT FALSE;
+	limit : REAL = 3;
+VAR
+	a, b, c, total, calls : INTEGER;
+	r : REAL = half * 1.5;
+	flag : BOOLEAN = big;
+
+PROCEDURE count;
+VAR
+	local : INTEGER = half;
+BEGIN
+	local := local + 1;
+	calls := calls + local
+END;
+
+BEGIN
+	a := size + negative;
+	total := 0;
+	FOR b := 1 TO size DO
+		CASE b OF
+			1..half: total := total + 1;
+			size: total := total + 100
+		ELSE
+		END;
+	count;
+	count;
+	c := calls
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"a":     5,
+		"total": 105,
+		"r":     7.5,
+		"flag":  true,
+		"c":     12,
+	})
+}
+
+func TestInterpreterConstantErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		err     string
+	}{{
+		program: `PROGRAM test; CONST a = 1; BEGIN a := 2 END.`,
+		err:     "1:34: cannot assign to constant a",
+	}, {
+		program: `PROGRAM test; VAR v : INTEGER; CONST a = v + 1; BEGIN END.`,
+		err:     "1:44: constant expression expected",
+	}, {
+		program: `PROGRAM test; CONST a : INTEGER = 1.5; BEGIN END.`,
+		err:     "1:21: cannot assign REAL to SMALLINT constant a",
+	}, {
+		program: `PROGRAM test; CONST a = 1 DIV 0; BEGIN END.`,
+		err:     "1:27: division by zero",
+	}, {
+		program: `PROGRAM test; CONST a = 1; a = 2; BEGIN END.`,
+		err:     "1:28: duplicate identifier: a",
+	}, {
+		program: `PROGRAM test; CONST a = 1; PROCEDURE p(VAR v : INTEGER); BEGIN END; BEGIN p(a) END.`,
+		err:     "1:75: VAR parameter v must be passed a variable",
+	}, {
+		program: `PROGRAM test; CONST a = 1; BEGIN FOR a := 1 TO 2 DO END.`,
+		err:     "1:38: a is not a variable",
+	}, {
+		program: `PROGRAM test; VAR v : INTEGER; x : INTEGER = v; BEGIN END.`,
+		err:     "1:46: constant expression expected",
+	}, {
+		program: `PROGRAM test; VAR v : BOOLEAN = 1; BEGIN END.`,
+		err:     "1:19: cannot assign SMALLINT to BOOLEAN variable v",
+	}}
+	for _, test := range tests {
+		err := newInterpreter(test.program).walk()
+		if _, ok := err.(*errSemantic); !ok || err.Error() != test.err {
+			t.Fatalf("expected %q for %q; got %v", test.err, test.program, err)
+		}
+	}
+}
+
+func TestInterpreterTypes(t *testing.T) {
+	program := `
+PROGRAM types;
+TYPE
+	count = INTEGER;
+	color = (red, green, blue);
+	primary = color;
+	digit = 0..9;
+	warm = red..green;
+	small = -(2 * 3)..Ord(blue);
+VAR
+	c : primary;
+	n, first, last, reds : count;
+	d : digit;
+	w : warm;
+	s : small;
+	z : (north, east, south, west);
+	higher, wrapped : BOOLEAN;
+
+FUNCTION next(c : color) : color;
+BEGIN
+	next := Succ(c)
+END;
+
+BEGIN
+	c := next(red);
+	n := Ord(c) * 10 + Ord(Pred(blue));
+	first := Ord(d) + Ord(w);
+	reds := 0;
+	FOR c := red TO blue DO
+		CASE c OF
+			red: reds := reds + 1;
+			green, blue: reds := reds + 10
+		END;
+	last := Ord(c);
+	d := 7;
+	d := d + 5;
+	s := -6;
+	z := Succ(south);
+	higher := blue > red;
+	wrapped := Succ(FALSE)
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"n":       11,
+		"first":   0,
+		"reds":    21,
+		"last":    2,
+		"d":       12,
+		"s":       -6,
+		"z":       3,
+		"higher":  true,
+		"wrapped": true,
+	})
+}
+
+func TestInterpreterRangeChecks(t *testing.T) {
+	tests := []struct {
+		program     string
+		rangeChecks bool
+		isError     bool
+	}{{
+		program: `PROGRAM test; TYPE digit = 0..9; VAR d : digit; BEGIN d := 9; d := d + 1 END.`,
+	}, {
+		program: `{$R+} PROGRAM test; TYPE digit = 0..9; VAR d : digit; BEGIN d := 9; d := d + 1 END.`,
+		isError: true,
+	}, {
+		program:     `PROGRAM test; TYPE digit = 0..9; VAR d : digit; BEGIN d := 9; d := d + 1 END.`,
+		rangeChecks: true,
+		isError:     true,
+	}, {
+		program:     `PROGRAM test; TYPE digit = 0..9; VAR d : digit; BEGIN d := 9; {$R-} d := d + 1 END.`,
+		rangeChecks: true,
+	}, {
+		program: `{$RANGECHECKS ON} PROGRAM test; TYPE color = (red, green); VAR c : color; BEGIN c := Succ(green) END.`,
+		isError: true,
+	}, {
+		program: `{$R+} PROGRAM test; VAR c : (red, green); BEGIN c := Pred(c) END.`,
+		isError: true,
+	}, {
+		program: `{$R+} PROGRAM test; TYPE digit = 0..9;
+PROCEDURE p(d : digit); BEGIN END;
+BEGIN p(3); p(3 * 4) END.`,
+		isError: true,
+	}, {
+		program: `{$R+} PROGRAM test; TYPE digit = 0..9; VAR d : digit; n : INTEGER; BEGIN n := 10; FOR d := 1 TO n DO END.`,
+		isError: true,
+	}, {
+		program: `{$R+} PROGRAM test; TYPE digit = 0..9; VAR d : digit; n : INTEGER; BEGIN n := 0; FOR d := 1 TO n DO END.`,
+	}}
+	for _, test := range tests {
+		i := newInterpreter(test.program)
+		i.options.rangeChecks = test.rangeChecks
+		err := i.walk()
+		if test.isError {
+			if _, ok := err.(*errRuntime); !ok {
+				t.Fatalf("expected a runtime error for %q; got %v", test.program, err)
+			}
+		} else if err != nil {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterTypeErrors(t *testing.T) {
+	tests := []string{
+		`PROGRAM test; TYPE t = INTEGER; t = REAL; BEGIN END.`,
+		`PROGRAM test; TYPE t = undefined; BEGIN END.`,
+		`PROGRAM test; VAR v : INTEGER; TYPE t = v; BEGIN END.`,
+		`PROGRAM test; TYPE t = 5..1; BEGIN END.`,
+		`PROGRAM test; TYPE t = 1..TRUE; BEGIN END.`,
+		`PROGRAM test; TYPE t = 1.5..2; BEGIN END.`,
+		`PROGRAM test; VAR n : INTEGER; TYPE t = 1..n; BEGIN END.`,
+		`PROGRAM test; TYPE a = (x, y); b = (y, z); BEGIN END.`,
+		`PROGRAM test; TYPE a = (x, y); b = (u, v); VAR c : a; BEGIN c := u END.`,
+		`PROGRAM test; TYPE a = (x, y); VAR c : a; BEGIN c := 0 END.`,
+		`PROGRAM test; TYPE a = (x, y); VAR n : INTEGER; BEGIN n := x + 1 END.`,
+		`PROGRAM test; TYPE a = (x, y); BEGIN IF x < 1 THEN END.`,
+		`PROGRAM test; TYPE digit = 0..9; VAR d : digit = 10; BEGIN END.`,
+		`PROGRAM test; TYPE digit = 0..9; CONST d : digit = 10; BEGIN END.`,
+		`PROGRAM test; BEGIN a := Ord(1.5) END.`,
+		`PROGRAM test; BEGIN a := Succ(1, 2) END.`,
+		`PROGRAM test; BEGIN Ord(1) END.`,
+	}
+	for _, program := range tests {
+		if err := newInterpreter(program).walk(); err == nil {
+			t.Fatalf("expected an error for %q", program)
+		}
+	}
+}
+
+func TestInterpreterArrays(t *testing.T) {
+	program := `
+PROGRAM arrays;
+TYPE
+	color = (red, green, blue);
+	vector = ARRAY[1..3] OF INTEGER;
+	matrix = ARRAY[1..3, color] OF REAL;
+VAR
+	v, w : vector;
+	m : matrix;
+	grid : ARRAY[0..2] OF ARRAY[BOOLEAN] OF INTEGER;
+	k, sum, first, copied, changed : INTEGER;
+	c : color;
+	trace : REAL;
+
+PROCEDURE double(VAR x : INTEGER);
+BEGIN
+	x := x * 2
+END;
+
+FUNCTION total(a : vector) : INTEGER;
+VAR
+	k, s : INTEGER;
+BEGIN
+	s := 0;
+	FOR k := 1 TO 3 DO
+		s := s + a[k];
+	a[1] := 100;
+	total := s
+END;
+
+BEGIN
+	FOR k := 1 TO 3 DO
+		v[k] := k * k;
+	w := v;
+	w[1] := 10;
+	double(v[2]);
+	sum := total(v);
+	first := v[1];
+	copied := w[1];
+	FOR k := 1 TO 3 DO
+		FOR c := red TO blue DO
+			m[k, c] := k * 10 + Ord(c);
+	trace := m[1, red] + m[2][green] + m[3, blue];
+	grid[2, TRUE] := 7;
+	grid[v[1] + 1][FALSE] := grid[2][TRUE] * 2;
+	changed := grid[2, FALSE]
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"sum":     18,
+		"first":   1,
+		"copied":  10,
+		"changed": 14,
+		"trace":   63.0,
+	})
+}
+
+func TestInterpreterArrayErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		// pos is the position of a runtime error, if one is expected.
+		pos position
+	}{{
+		program: `PROGRAM test; VAR a : ARRAY[LONGINT] OF INTEGER; BEGIN END.`,
+	}, {
+		program: `PROGRAM test; VAR a : ARRAY[1..2] OF INTEGER; BEGIN a[TRUE] := 1 END.`,
+	}, {
+		program: `PROGRAM test; VAR a : ARRAY[1..2] OF INTEGER; BEGIN a[1] := 1.5 END.`,
+	}, {
+		program: `PROGRAM test; VAR a : ARRAY[1..2] OF INTEGER; n : INTEGER; BEGIN n[1] := 1 END.`,
+	}, {
+		program: `PROGRAM test; VAR a : ARRAY[1..2] OF INTEGER; b : ARRAY[1..2] OF INTEGER; BEGIN a := b END.`,
+	}, {
+		program: `PROGRAM test; VAR a, b : ARRAY[1..2] OF INTEGER; BEGIN IF a = b THEN END.`,
+	}, {
+		program: `PROGRAM test; CONST c = 1; BEGIN c[1] := 1 END.`,
+	}, {
+		program: `PROGRAM test;
+VAR
+	a : ARRAY[1..10] OF INTEGER;
+	n : INTEGER;
+BEGIN
+	n := 11;
+	a[n - 1] := a[n]
+END.`,
+		pos: position{7, 16},
+	}, {
+		program: `PROGRAM test;
+VAR
+	a : ARRAY[1..2, 1..2] OF INTEGER;
+	n : INTEGER;
+BEGIN
+	a[1, n] := 1
+END.`,
+		pos: position{6, 7},
+	}}
+	for _, test := range tests {
+		err := newInterpreter(test.program).walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		runtimeErr, ok := err.(*errRuntime)
+		if ok != (test.pos != position{}) {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+		if ok && (runtimeErr.code != errCodeRangeCheck || runtimeErr.pos != test.pos) {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterDynamicArrays(t *testing.T) {
+	program := `
+PROGRAM dynamic;
+TYPE
+	TNumbers = ARRAY OF INTEGER;
+	TMatrix = ARRAY OF ARRAY OF REAL;
+VAR
+	a, b, c : TNumbers;
+	m : TMatrix;
+	fixed : ARRAY[1..4] OF INTEGER;
+	k, count, first, last, shared, kept, copied, sum, fixedSum, cell : INTEGER;
+	rows, columns, lowIndex, highIndex, emptyHigh, tail : INTEGER;
+	empty, assigned, cleared : BOOLEAN;
+
+FUNCTION Total(values : ARRAY OF INTEGER) : INTEGER;
+VAR
+	k, sum : INTEGER;
+BEGIN
+	sum := 0;
+	FOR k := Low(values) TO High(values) DO
+		sum := sum + values[k];
+	Total := sum
+END;
+
+PROCEDURE Fill(VAR values : ARRAY OF INTEGER; value : INTEGER);
+VAR
+	k : INTEGER;
+BEGIN
+	FOR k := 0 TO High(values) DO
+		values[k] := value
+END;
+
+PROCEDURE Grow(VAR values : TNumbers);
+BEGIN
+	SetLength(values, Length(values) + 1);
+	values[High(values)] := 99
+END;
+
+BEGIN
+	emptyHigh := High(a);
+	empty := a = NIL;
+	SetLength(a, 3);
+	assigned := a <> NIL;
+	FOR k := 0 TO 2 DO
+		a[k] := k + 1;
+	count := Length(a);
+	b := a;
+	b[0] := 10;
+	shared := a[0];
+	SetLength(b, 5);
+	b[1] := 20;
+	kept := a[1];
+	first := b[0];
+	last := b[4];
+	c := Copy(a, 1, 5);
+	c[0] := 7;
+	copied := a[1];
+	tail := Length(c);
+	sum := Total(a);
+	Fill(fixed, 2);
+	fixedSum := Total(fixed);
+	lowIndex := Low(fixed);
+	highIndex := High(fixed);
+	Grow(a);
+	SetLength(m, 2, 3);
+	m[1, 2] := 1.5;
+	rows := Length(m);
+	columns := Length(m[1]);
+	cell := Trunc(m[1][2] * 2);
+	SetLength(c, 0);
+	cleared := NIL = c
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"emptyhigh": -1,
+		"count":     3,
+		"shared":    10,
+		"kept":      2,
+		"first":     10,
+		"last":      0,
+		"copied":    2,
+		"tail":      2,
+		"sum":       15,
+		"fixedsum":  8,
+		"lowindex":  1,
+		"highindex": 4,
+		"rows":      2,
+		"columns":   3,
+		"cell":      3,
+		"empty":     true,
+		"assigned":  true,
+		"cleared":   true,
+	})
+	value, _ := i.globalScope.get("a")
+	if a := arrayElements(value); len(a) != 4 || a[3] != 99 {
+		t.Fatalf("expected Grow to append 99; got %v", a)
+	}
+}
+
+func TestInterpreterDynamicArrayErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		// msg is the message of a runtime error, if one is expected.
+		msg string
+	}{{
+		program: `PROGRAM test; VAR a : ARRAY OF INTEGER; BEGIN a[TRUE] := 1 END.`,
+	}, {
+		program: `PROGRAM test; VAR a : ARRAY OF INTEGER; b : ARRAY OF REAL; BEGIN a := b END.`,
+	}, {
+		program: `PROGRAM test; VAR a : ARRAY OF INTEGER; b : ARRAY[0..1] OF INTEGER; BEGIN a := b END.`,
+	}, {
+		program: `PROGRAM test; VAR a : ARRAY OF INTEGER; BEGIN SetLength(a, 1, 2) END.`,
+	}, {
+		program: `PROGRAM test; VAR a : ARRAY[0..1] OF INTEGER; BEGIN SetLength(a, 1) END.`,
+	}, {
+		program: `PROGRAM test; PROCEDURE P(a : ARRAY OF INTEGER); BEGIN SetLength(a, 1) END; BEGIN END.`,
+	}, {
+		program: `PROGRAM test; VAR b : ARRAY OF INTEGER; PROCEDURE P(a : ARRAY OF INTEGER); BEGIN a := b END; BEGIN END.`,
+	}, {
+		program: `PROGRAM test; VAR b : ARRAY[1..2] OF REAL; PROCEDURE P(a : ARRAY OF INTEGER); BEGIN END; BEGIN P(b) END.`,
+	}, {
+		program: `PROGRAM test; VAR x : REAL; BEGIN x := High(x) END.`,
+	}, {
+		program: `PROGRAM test; VAR a : ARRAY[0..1] OF INTEGER; BEGIN IF a = NIL THEN END.`,
+	}, {
+		program: `PROGRAM test; VAR a : ARRAY OF INTEGER; BEGIN IF a < NIL THEN END.`,
+	}, {
+		program: `PROGRAM test; VAR a : ARRAY OF INTEGER; BEGIN SetLength(a, 2); a[2] := 1 END.`,
+		msg:     "runtime error 201 at 1:66: index 2 is out of bounds 0..1",
+	}, {
+		program: `PROGRAM test; VAR a : ARRAY OF INTEGER; n : INTEGER; BEGIN n := a[-1] END.`,
+		msg:     "runtime error 201 at 1:67: index -1 is out of bounds 0..-1",
+	}, {
+		program: `PROGRAM test; VAR a : ARRAY OF INTEGER; BEGIN SetLength(a, -1) END.`,
+		msg:     "runtime error 201 at 1:47: invalid length -1 for ARRAY OF SMALLINT",
+	}}
+	for _, test := range tests {
+		err := newInterpreter(test.program).walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		if _, ok := err.(*errRuntime); ok != (test.msg != "") || ok && err.Error() != test.msg {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterRecords(t *testing.T) {
+	program := `
+PROGRAM records;
+TYPE
+	point = RECORD
+		x, y : INTEGER
+	END;
+	kind = (circle, rectangle);
+	shape = RECORD
+		origin : point;
+		CASE k : kind OF
+			circle: (radius : REAL);
+			rectangle: (width, height : REAL)
+	END;
+VAR
+	p, q : point;
+	shapes : ARRAY[1..2] OF shape;
+	n, px, qx, sx, ox, cy : INTEGER;
+	area : REAL;
+	pair : RECORD
+		a : point;
+		CASE BOOLEAN OF
+			TRUE: (b : INTEGER)
+	END;
+
+PROCEDURE move(VAR p : point; dx : INTEGER);
+BEGIN
+	p.x := p.x + dx
+END;
+
+FUNCTION origin : point;
+VAR
+	p : point;
+BEGIN
+	p.x := 1;
+	p.y := 2;
+	origin := p
+END;
+
+FUNCTION corner : point;
+BEGIN
+	corner.x := 3;
+	corner.y := 4
+END;
+
+BEGIN
+	p.x := 1;
+	p.y := 2;
+	q := p;
+	q.x := 10;
+	move(p, 5);
+	px := p.x;
+	qx := q.x;
+	n := 1;
+	WITH shapes[n] DO
+	BEGIN
+		n := 2;
+		k := circle;
+		radius := 2;
+		origin.x := 3
+	END;
+	WITH shapes[n], origin DO
+	BEGIN
+		k := rectangle;
+		width := 2;
+		height := 3;
+		x := 4
+	END;
+	area := 0;
+	FOR n := 1 TO 2 DO
+		WITH shapes[n] DO
+			CASE k OF
+				circle: area := area + radius * radius;
+				rectangle: area := area + width * height
+			END;
+	sx := shapes[1].origin.x * 10 + shapes[2].origin.x;
+	ox := origin.y;
+	cy := corner.y;
+	WITH pair, a DO
+	BEGIN
+		x := 7;
+		b := x
+	END
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"px":   6,
+		"qx":   10,
+		"area": 10.0,
+		"sx":   34,
+		"ox":   2,
+		"cy":   4,
+	})
+	pair, _ := i.globalScope.get("pair")
+	if b := pair.([]interface{})[1]; b != 7 {
+		t.Fatalf("expected to get 7 for pair.b; got %v", b)
+	}
+}
+
+func TestInterpreterRecordErrors(t *testing.T) {
+	tests := []string{
+		`PROGRAM test; TYPE r = RECORD a, a : INTEGER END; BEGIN END.`,
+		`PROGRAM test; TYPE r = RECORD a : INTEGER; CASE a : BOOLEAN OF TRUE: () END; BEGIN END.`,
+		`PROGRAM test; TYPE r = RECORD CASE REAL OF 1: () END; BEGIN END.`,
+		`PROGRAM test; TYPE r = RECORD CASE BOOLEAN OF 1: () END; BEGIN END.`,
+		`PROGRAM test; VAR v : RECORD a : INTEGER END; BEGIN v.b := 1 END.`,
+		`PROGRAM test; VAR v : RECORD a : INTEGER END; BEGIN v.a := TRUE END.`,
+		`PROGRAM test; VAR n : INTEGER; BEGIN n.a := 1 END.`,
+		`PROGRAM test; VAR n : INTEGER; BEGIN WITH n DO END.`,
+		`PROGRAM test; VAR v : RECORD a : INTEGER END; BEGIN WITH v DO FOR a := 1 TO 2 DO END.`,
+		`PROGRAM test; VAR v : RECORD a : INTEGER END; w : RECORD a : INTEGER END; BEGIN v := w END.`,
+	}
+	for _, program := range tests {
+		if err := newInterpreter(program).walk(); err == nil {
+			t.Fatalf("expected an error for %q", program)
+		}
+	}
+}
+
+func TestInterpreterSets(t *testing.T) {
+	program := `
+PROGRAM sets;
+TYPE
+	day = (mon, tue, wed, thu, fri, sat, sun);
+	days = SET OF day;
+CONST
+	weekend = [sat, sun];
+VAR
+	work, all, none, some : days;
+	digits : SET OF 0..9;
+	d : day;
+	n, count, primes : INTEGER;
+	hassat, issubset, issuperset, equal, differ, inempty : BOOLEAN;
+
+BEGIN
+	all := [mon..sun];
+	work := all - weekend;
+	none := work * weekend;
+	some := [mon, wed] + [fri];
+	count := 0;
+	FOR d := mon TO sun DO
+		IF d IN work THEN
+			count := count + 1;
+	hassat := sat IN all - work;
+	issubset := some <= work;
+	issuperset := all >= some + weekend;
+	equal := none = [];
+	differ := work + weekend <> all;
+	n := 5;
+	digits := [2, 3, n, 7];
+	primes := 0;
+	FOR n := 0 TO 9 DO
+		IF n IN digits THEN
+			primes := primes * 10 + n;
+	inempty := 300 IN []
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"count":      5,
+		"hassat":     true,
+		"issubset":   true,
+		"issuperset": true,
+		"equal":      true,
+		"differ":     false,
+		"primes":     2357,
+		"inempty":    false,
+	})
+}
+
+func TestInterpreterSetErrors(t *testing.T) {
+	tests := []struct {
+		program   string
+		isRuntime bool
+	}{{
+		program: `PROGRAM test; VAR s : SET OF INTEGER; BEGIN END.`,
+	}, {
+		program: `PROGRAM test; VAR s : SET OF -1..5; BEGIN END.`,
+	}, {
+		program: `PROGRAM test; VAR s : SET OF 0..9; BEGIN s := [TRUE] END.`,
+	}, {
+		program: `PROGRAM test; VAR s : SET OF 0..9; BEGIN s := [1, FALSE] END.`,
+	}, {
+		program: `PROGRAM test; VAR s : SET OF 0..9; BEGIN IF s < [1] THEN END.`,
+	}, {
+		program: `PROGRAM test; VAR s : SET OF 0..9; BEGIN IF TRUE IN s THEN END.`,
+	}, {
+		program: `PROGRAM test; VAR s : SET OF 0..9; BEGIN s := s + 1 END.`,
+	}, {
+		program:   `PROGRAM test; VAR s : SET OF 0..9; n : INTEGER; BEGIN n := 256; s := [n] END.`,
+		isRuntime: true,
+	}, {
+		program: `PROGRAM test; VAR s : SET OF 0..10; BEGIN s := [20] END.`,
+	}, {
+		program: `PROGRAM test; VAR s : SET OF 0..10; BEGIN s := [1, 3..12] END.`,
+	}, {
+		program: `PROGRAM test; TYPE small = SET OF 0..10; CONST c : small = [11]; BEGIN END.`,
+	}, {
+		program: `PROGRAM test; VAR s : SET OF 0..10 = [0, 99]; BEGIN END.`,
+	}, {
+		program: `PROGRAM test; CONST big = [99]; VAR s : SET OF 0..10 = big; BEGIN END.`,
+	}, {
+		program:   `{$R+} PROGRAM test; VAR s : SET OF 0..10; n : INTEGER; BEGIN n := 20; s := [n] END.`,
+		isRuntime: true,
+	}}
+	for _, test := range tests {
+		err := newInterpreter(test.program).walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		if _, ok := err.(*errRuntime); ok != test.isRuntime {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterPointers(t *testing.T) {
+	program := `
+PROGRAM pointers;
+TYPE
+	list = ^item;
+	item = RECORD
+		value : INTEGER;
+		next : list
+	END;
+VAR
+	head, p, q : list;
+	n, sum, count : INTEGER;
+	shared : ^INTEGER;
+	alias : ^INTEGER;
+	same, empty : BOOLEAN;
+
+PROCEDURE push(VAR head : list; value : INTEGER);
+VAR
+	p : list;
+BEGIN
+	New(p);
+	p^.value := value;
+	p^.next := head;
+	head := p
+END;
+
+BEGIN
+	head := NIL;
+	empty := head = NIL;
+	FOR n := 1 TO 4 DO
+		push(head, n);
+	sum := 0;
+	p := head;
+	WHILE p <> NIL DO
+	BEGIN
+		sum := sum * 10 + p^.value;
+		p := p^.next
+	END;
+	p := head^.next;
+	head^.next := p^.next;
+	Dispose(p);
+	count := 0;
+	p := head;
+	WHILE p <> NIL DO
+	BEGIN
+		count := count + 1;
+		q := p;
+		p := p^.next;
+		IF count > 1 THEN
+			Dispose(q)
+	END;
+	New(shared);
+	alias := shared;
+	alias^ := 42;
+	n := shared^;
+	same := alias = shared
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"sum":   4321,
+		"count": 3,
+		"n":     42,
+		"same":  true,
+		"empty": true,
+	})
+	leaks := i.heap.leaks()
+	if len(leaks) != 2 || leaks[0].id != 4 || leaks[1].id != 5 {
+		t.Fatalf("expected the cells #4 and #5 to leak; got %v", leaks)
+	}
+	if len(i.heap.cells) != 2 {
+		t.Fatalf("expected the heap to drop disposed cells; got %d cells", len(i.heap.cells))
+	}
+}
+
+func TestInterpreterPointerErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		code    int
+	}{{
+		program: `PROGRAM test; TYPE p = ^undefined; BEGIN END.`,
+	}, {
+		program: `PROGRAM test; VAR n : INTEGER; TYPE p = ^n; BEGIN END.`,
+	}, {
+		program: `PROGRAM test; VAR n : INTEGER; BEGIN n^ := 1 END.`,
+	}, {
+		program: `PROGRAM test; VAR p : ^INTEGER; q : ^BOOLEAN; BEGIN p := q END.`,
+	}, {
+		program: `PROGRAM test; VAR p : ^INTEGER; BEGIN p := 0 END.`,
+	}, {
+		program: `PROGRAM test; VAR p : ^INTEGER; BEGIN IF p < NIL THEN END.`,
+	}, {
+		program: `PROGRAM test; VAR n : INTEGER; BEGIN New(n) END.`,
+	}, {
+		program: `PROGRAM test; BEGIN Dispose(NIL) END.`,
+	}, {
+		program: `PROGRAM test; VAR p : ^INTEGER; BEGIN p := NIL; p^ := 1 END.`,
+		code:    errCodeAccessViolation,
+	}, {
+		program: `PROGRAM test; VAR p, q : ^INTEGER; n : INTEGER; BEGIN New(p); q := p; Dispose(p); n := q^ END.`,
+		code:    errCodeAccessViolation,
+	}, {
+		program: `PROGRAM test; VAR p : ^INTEGER; BEGIN New(p); Dispose(p); Dispose(p) END.`,
+		code:    errCodeInvalidPointer,
+	}, {
+		program: `PROGRAM test; VAR p : ^INTEGER; BEGIN p := NIL; Dispose(p) END.`,
+		code:    errCodeInvalidPointer,
+	}}
+	for _, test := range tests {
+		err := newInterpreter(test.program).walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		runtimeErr, ok := err.(*errRuntime)
+		if ok != (test.code != 0) || ok && runtimeErr.code != test.code {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterStrings(t *testing.T) {
+	program := `
+PROGRAM strings;
+TYPE
+	letter = 'a'..'z';
+VAR
+	counts : ARRAY['a'..'z'] OF INTEGER;
+	vowels : SET OF CHAR;
+	s, t, u, empty : STRING;
+	short : STRING[5];
+	c, first, last, upper : CHAR;
+	l : letter;
+	n, len, at, missing, code, nvowels, distinct : INTEGER;
+	less, equal, charless, mixed : BOOLEAN;
+
+BEGIN
+	s := 'Hello';
+	t := s + ', ' + 'world' + #33;
+	len := Length(t);
+	first := t[1];
+	last := t[len];
+	s[1] := 'J';
+	at := Pos('world', t);
+	missing := Pos('xyz', t);
+	short := t;
+	t := Copy(t, 8, 100);
+	Insert('big ', t, 1);
+	Delete(t, 5, 5);
+	Insert('...', short, 3);
+	empty := Copy(s, 10, 2);
+	upper := UpCase('q');
+	code := Ord('A') + Ord(Chr(98));
+	less := 'abc' < 'abd';
+	equal := Concat('ab', 'c', 'd') = 'abcd';
+	charless := 'a' < 'b';
+	mixed := 'a' = s[2] + '';
+	vowels := ['a', 'e', 'i', 'o', 'u'];
+	nvowels := 0;
+	FOR c := 'a' TO 'z' DO
+		counts[c] := 0;
+	FOR n := 1 TO Length('banana split') DO
+	BEGIN
+		u := Copy('banana split', n, 1);
+		c := u[1];
+		IF c IN ['a'..'z'] THEN
+			counts[c] := counts[c] + 1;
+		IF c IN vowels THEN
+			nvowels := nvowels + 1
+	END;
+	distinct := 0;
+	FOR l := 'a' TO 'z' DO
+		CASE l OF
+			'a', 'e', 'i', 'o', 'u': ;
+			'b'..'d', 'f'..'h', 'j'..'n', 'p'..'t', 'v'..'z':
+				IF counts[l] > 0 THEN
+					distinct := distinct + 1
+		END
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"s":        "Jello",
+		"t":        "big !",
+		"short":    "He...",
+		"empty":    "",
+		"len":      13,
+		"first":    byte('H'),
+		"last":     byte('!'),
+		"at":       8,
+		"missing":  0,
+		"upper":    byte('Q'),
+		"code":     163,
+		"less":     true,
+		"equal":    true,
+		"charless": true,
+		"mixed":    false,
+		"nvowels":  4,
+		"distinct": 6,
+	})
+}
+
+func TestInterpreterStringErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		code    int
+	}{{
+		program: `PROGRAM test; VAR s : STRING; BEGIN s := 'abc END.`,
+	}, {
+		program: `PROGRAM test; VAR s : STRING; BEGIN s := #256 END.`,
+	}, {
+		program: `PROGRAM test; VAR s : STRING[0]; BEGIN END.`,
+	}, {
+		program: `PROGRAM test; VAR s : STRING[256]; BEGIN END.`,
+	}, {
+		program: `PROGRAM test; VAR c : CHAR; BEGIN c := 'ab' END.`,
+	}, {
+		program: `PROGRAM test; VAR s : STRING; BEGIN s := 1 END.`,
+	}, {
+		program: `PROGRAM test; VAR s : STRING; BEGIN s := 'a' + 1 END.`,
+	}, {
+		program: `PROGRAM test; VAR b : BOOLEAN; BEGIN b := 'a' < 1 END.`,
+	}, {
+		program: `PROGRAM test; VAR c : CHAR; s : STRING; BEGIN c := s['a'] END.`,
+	}, {
+		program: `PROGRAM test; BEGIN Insert('a', 'b', 1) END.`,
+	}, {
+		program: `PROGRAM test; VAR c : CHAR; BEGIN c := UpCase('ab') END.`,
+	}, {
+		program: `PROGRAM test; VAR c : CHAR; s : STRING; BEGIN s := 'abc'; c := s[4] END.`,
+		code:    errCodeRangeCheck,
+	}, {
+		program: `PROGRAM test; VAR s : STRING; BEGIN s[1] := 'a' END.`,
+		code:    errCodeRangeCheck,
+	}, {
+		program: `{$R+} PROGRAM test; VAR c : CHAR; n : INTEGER; BEGIN n := 256; c := Chr(n) END.`,
+		code:    errCodeRangeCheck,
+	}, {
+		program: `PROGRAM test; VAR c : CHAR; BEGIN c := Chr(300) END.`,
+	}, {
+		program: `PROGRAM test; CONST base = 200; VAR c : CHAR; BEGIN c := Chr(base + 100) END.`,
+	}}
+	for _, test := range tests {
+		err := newInterpreter(test.program).walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		runtimeErr, ok := err.(*errRuntime)
+		if ok != (test.code != 0) || ok && runtimeErr.code != test.code {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterWrite(t *testing.T) {
+	tests := []struct {
+		program string
+		output  string
+	}{{
+		program: `PROGRAM test; BEGIN WriteLn('Hello, ', 'world', '!'); WriteLn END.`,
+		output:  "Hello, world!\n\n",
+	}, {
+		program: `PROGRAM test; VAR n : INTEGER; BEGIN n := 42; Write(n, ' ', -n:5, n:1, '|', 'ab':4, 'x':-3, TRUE:6) END.`,
+		output:  "42   -4242|  abx  TRUE",
+	}, {
+		program: `PROGRAM test; VAR x : REAL; BEGIN x := 3.14159; WriteLn(x:0:2, ' ', x:8:3, ' ', -x:4:0, ' ', x:10:-1) END.`,
+		output:  "3.14    3.142   -3          3\n",
+	}, {
+		program: `PROGRAM test; BEGIN Write(2.5:0:0, ' ', -2.5:0:0, ' ', 0.125:0:2, ' ', 1.5:3:0) END.`,
+		output:  "3 -3 0.13   2",
+	}, {
+		program: `PROGRAM test; BEGIN WriteLn(1.0); WriteLn(-1234.5); WriteLn(0.5:10); WriteLn(2.0:3) END.`,
+		output:  " 1.0000000000E+00\n-1.2345000000E+03\n 5.000E-01\n 2.0E+00\n",
+	}, {
+		program: `PROGRAM test; TYPE color = (red, green); VAR c : color; s : STRING[3]; BEGIN c := green; s := 'abcdef'; Write(c, ' ', red:5, ' ', s, 'z', #65) END.`,
+		output:  "green   red abczA",
+	}}
+	for _, test := range tests {
+		var output strings.Builder
+		i := newInterpreter(test.program)
+		i.output = &output
+		if err := i.walk(); err != nil {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+		if output.String() != test.output {
+			t.Fatalf("expected %q for %q; got %q", test.output, test.program, output.String())
+		}
+	}
+}
+
+func TestInterpreterRead(t *testing.T) {
+	program := `
+PROGRAM test;
+VAR
+	a, b, c, sum : INTEGER;
+	x : REAL;
+	name : STRING;
+	short : STRING[3];
+	first, second, last : CHAR;
+BEGIN
+	Read(a, b);
+	ReadLn(c);
+	ReadLn(x);
+	ReadLn(name);
+	Read(first, second);
+	ReadLn;
+	ReadLn(short);
+	sum := 0;
+	REPEAT
+		Read(a);
+		sum := sum + a
+	UNTIL a = 0;
+	Read(last);
+	WriteLn(name, ': ', a + b + c, ' ', x:0:1, ' ', first, second, ' ', short, ' ', sum, ' ', Ord(last))
+END.
+`
+	input := "1 2\n  3 ignored\n-2.5e1\r\nJohn Smith\nxyz\nabcdef\n10 20\n\n 30"
+	var output strings.Builder
+	i := newInterpreter(program)
+	i.input = strings.NewReader(input)
+	i.output = &output
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	if expect := "John Smith: 5 -25.0 xy abc 60 26\n"; output.String() != expect {
+		t.Fatalf("expected %q; got %q", expect, output.String())
+	}
+}
+
+func TestInterpreterIOErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		input   string
+		code    int
+	}{{
+		program: `PROGRAM test; VAR n : INTEGER; BEGIN n := 1:2 END.`,
+	}, {
+		program: `PROGRAM test; VAR n : INTEGER; BEGIN n := Ord(1:2) END.`,
+	}, {
+		program: `PROGRAM test; BEGIN Write(1:2:3) END.`,
+	}, {
+		program: `PROGRAM test; BEGIN Write(1.5:'a') END.`,
+	}, {
+		program: `PROGRAM test; VAR s : SET OF CHAR; BEGIN Write(s) END.`,
+	}, {
+		program: `PROGRAM test; BEGIN Read(1) END.`,
+	}, {
+		program: `PROGRAM test; VAR b : BOOLEAN; BEGIN Read(b) END.`,
+	}, {
+		program: `PROGRAM test; VAR n : INTEGER; BEGIN Read(n) END.`,
+		input:   "12x",
+		code:    errCodeInvalidNumeric,
+	}, {
+		program: `PROGRAM test; VAR x : REAL; BEGIN ReadLn(x) END.`,
+		input:   "1.5.5",
+		code:    errCodeInvalidNumeric,
+	}, {
+		program: `{$R+} PROGRAM test; VAR n : 1..10; BEGIN Read(n) END.`,
+		input:   "11",
+		code:    errCodeRangeCheck,
+	}}
+	for _, test := range tests {
+		i := newInterpreter(test.program)
+		i.input = strings.NewReader(test.input)
+		err := i.walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		runtimeErr, ok := err.(*errRuntime)
+		if ok != (test.code != 0) || ok && runtimeErr.code != test.code {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterTextFiles(t *testing.T) {
+	program := `
+PROGRAM files;
+VAR
+	f, g : TEXT;
+	line : STRING;
+	n, sum, lines, chars : INTEGER;
+	c : CHAR;
+
+PROCEDURE copyfile(VAR source, target : TEXT);
+VAR
+	line : STRING;
+BEGIN
+	WHILE NOT Eof(source) DO
+	BEGIN
+		ReadLn(source, line);
+		WriteLn(target, '> ', line)
+	END
+END;
+
+BEGIN
+	Assign(f, 'numbers.txt');
+	Rewrite(f);
+	FOR n := 1 TO 3 DO
+		Write(f, n * 10:4);
+	WriteLn(f);
+	Close(f);
+	Append(f);
+	WriteLn(f, 'total');
+	Close(f);
+
+	Reset(f);
+	sum := 0;
+	WHILE NOT Eoln(f) DO
+	BEGIN
+		Read(f, n);
+		sum := sum + n
+	END;
+	ReadLn(f);
+	ReadLn(f, line);
+	Close(f);
+
+	Assign(g, 'data/input.txt');
+	Reset(g);
+	lines := 0;
+	chars := 0;
+	WHILE NOT Eof(g) DO
+	BEGIN
+		WHILE NOT Eoln(g) DO
+		BEGIN
+			Read(g, c);
+			chars := chars + 1
+		END;
+		ReadLn(g);
+		lines := lines + 1
+	END;
+	Reset(g);
+	Assign(f, 'copy.txt');
+	Rewrite(f);
+	copyfile(g, f);
+	Close(f);
+	Close(g);
+	WriteLn(sum, ' ', line, ' ', lines, ' ', chars)
+END.
+`
+	files := newMemFS()
+	files.writeFile("data/input.txt", []byte("first line\nsecond\n\nlast"))
+	var output strings.Builder
+	i := newInterpreter(program)
+	i.files = files
+	i.output = &output
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	if expect := "60 total 4 20\n"; output.String() != expect {
+		t.Fatalf("expected %q; got %q", expect, output.String())
+	}
+	for name, expect := range map[string]string{
+		"numbers.txt": "  10  20  30\ntotal\n",
+		"copy.txt":    "> first line\n> second\n> \n> last\n",
+	} {
+		data, err := fs.ReadFile(files, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expect {
+			t.Fatalf("expected %q in %v; got %q", expect, name, data)
+		}
+	}
+}
+
+func TestInterpreterDefaultFileSystem(t *testing.T) {
+	program := `
+PROGRAM sandbox;
+VAR
+	f : TEXT;
+BEGIN
+	Assign(f, 'sandbox.txt');
+	Rewrite(f);
+	WriteLn(f, 'kept in memory');
+	Close(f)
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := fs.ReadFile(i.files, "sandbox.txt"); err != nil || string(data) != "kept in memory\n" {
+		t.Fatalf("expected the file in the default filesystem; got %q, %v", data, err)
+	}
+	if _, err := os.Stat("sandbox.txt"); !os.IsNotExist(err) {
+		os.Remove("sandbox.txt")
+		t.Fatalf("expected the program not to write to the current directory")
+	}
+}
+
+func TestInterpreterIOResult(t *testing.T) {
+	program := `
+PROGRAM ioresult;
+VAR
+	f : TEXT;
+	n, missing, again, numeric, skipped, notopen : INTEGER;
+	ended : BOOLEAN;
+BEGIN
+	Assign(f, 'missing.txt');
+	{$I-}
+	Reset(f);
+	missing := IOResult;
+	again := IOResult;
+	Assign(f, 'bad.txt');
+	Reset(f);
+	Read(f, n);
+	numeric := IOResult;
+	Reset(f);
+	Close(f);
+	Close(f);
+	Close(f);
+	ended := Eof(f);
+	notopen := IOResult;
+	Rewrite(f);
+	skipped := IOResult;
+	{$I+}
+	Close(f)
+END.
+`
+	files := newMemFS()
+	files.writeFile("bad.txt", []byte("x1"))
+	i := newInterpreter(program)
+	i.files = files
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"missing": errCodeFileNotFound,
+		"again":   0,
+		"numeric": errCodeInvalidNumeric,
+		"notopen": errCodeNotOpen,
+		"ended":   true,
+		"skipped": 0,
+	})
+}
+
+func TestInterpreterFileErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		code    int
+	}{{
+		program: `PROGRAM test; VAR f, g : TEXT; BEGIN f := g END.`,
+	}, {
+		program: `PROGRAM test; VAR f : TEXT; PROCEDURE p(f : TEXT); BEGIN END; BEGIN p(f) END.`,
+	}, {
+		program: `PROGRAM test; VAR f : TEXT; BEGIN Assign(f, 1) END.`,
+	}, {
+		program: `PROGRAM test; BEGIN Reset('a.txt') END.`,
+	}, {
+		program: `PROGRAM test; VAR f : TEXT; BEGIN WriteLn(f:3) END.`,
+	}, {
+		program: `PROGRAM test; VAR f : TEXT; b : BOOLEAN; BEGIN b := Eof(f, f) END.`,
+	}, {
+		program: `PROGRAM test; VAR f : TEXT; BEGIN Write('a', f) END.`,
+	}, {
+		program: `PROGRAM test; VAR f : TEXT; BEGIN Reset(f) END.`,
+		code:    errCodeNotAssigned,
+	}, {
+		program: `PROGRAM test; VAR f : TEXT; BEGIN Assign(f, 'missing.txt'); Reset(f) END.`,
+		code:    errCodeFileNotFound,
+	}, {
+		program: `PROGRAM test; VAR f : TEXT; BEGIN Assign(f, '../escape.txt'); Rewrite(f) END.`,
+		code:    errCodePathNotFound,
+	}, {
+		program: `PROGRAM test; VAR f : TEXT; BEGIN Assign(f, 'missing.txt'); Append(f) END.`,
+		code:    errCodeFileNotFound,
+	}, {
+		program: `PROGRAM test; VAR f : TEXT; BEGIN WriteLn(f, 1) END.`,
+		code:    errCodeNotOpen,
+	}, {
+		program: `PROGRAM test; VAR f : TEXT; BEGIN Close(f) END.`,
+		code:    errCodeNotOpen,
+	}, {
+		program: `PROGRAM test; VAR f : TEXT; BEGIN Assign(f, 'out.txt'); Rewrite(f); ReadLn(f) END.`,
+		code:    errCodeNotOpenInput,
+	}, {
+		program: `PROGRAM test; VAR f : TEXT; b : BOOLEAN; BEGIN Assign(f, 'out.txt'); Rewrite(f); b := Eof(f) END.`,
+		code:    errCodeNotOpenInput,
+	}, {
+		program: `PROGRAM test; VAR f : TEXT; BEGIN Assign(f, 'out.txt'); Rewrite(f); Close(f); Reset(f); Write(f, 1) END.`,
+		code:    errCodeNotOpenOutput,
+	}}
+	for _, test := range tests {
+		i := newInterpreter(test.program)
+		i.files = newMemFS()
+		err := i.walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		runtimeErr, ok := err.(*errRuntime)
+		if ok != (test.code != 0) || ok && runtimeErr.code != test.code {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterTypedFiles(t *testing.T) {
+	program := `
+PROGRAM typed;
+TYPE
+	color = (red, green, blue);
+	item = RECORD
+		id : INTEGER;
+		name : STRING[5];
+		price : REAL;
+		color : color;
+		tags : SET OF 0..15;
+		codes : ARRAY[1..2] OF CHAR;
+		active : BOOLEAN
+	END;
+VAR
+	f : FILE OF item;
+	g : FILE OF INTEGER;
+	it : item;
+	n, size, pos, sum, truncated, last : INTEGER;
+	ended : BOOLEAN;
+	name : STRING;
+BEGIN
+	Assign(f, 'items.dat');
+	Rewrite(f);
+	FOR n := 1 TO 4 DO
+	BEGIN
+		it.id := n;
+		it.name := 'item' + Chr(Ord('0') + n);
+		it.price := n * 1.5;
+		it.color := green;
+		it.tags := [n, 15];
+		it.codes[1] := 'x';
+		it.codes[2] := Chr(Ord('a') + n);
+		it.active := n IN [1, 3];
+		Write(f, it)
+	END;
+	Close(f);
+
+	Reset(f);
+	size := FileSize(f);
+	Seek(f, 2);
+	Read(f, it);
+	pos := FilePos(f);
+	name := it.name + it.codes[2];
+	Seek(f, 1);
+	it.id := 20;
+	Write(f, it);
+	Seek(f, 0);
+	sum := 0;
+	WHILE NOT Eof(f) DO
+	BEGIN
+		Read(f, it);
+		IF 15 IN it.tags THEN
+			sum := sum + it.id
+	END;
+	Seek(f, 3);
+	Truncate(f);
+	truncated := FileSize(f);
+	Close(f);
+
+	Assign(g, 'numbers.dat');
+	Rewrite(g);
+	Write(g, 1, -2, 258);
+	Seek(g, 2);
+	Read(g, last);
+	ended := Eof(g);
+	Close(g)
+END.
+`
+	files := newMemFS()
+	i := newInterpreter(program)
+	i.files = files
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"size":      4,
+		"pos":       3,
+		"name":      "item3d",
+		"sum":       28,
+		"truncated": 3,
+		"last":      258,
+		"ended":     true,
+	})
+	data, err := fs.ReadFile(files, "numbers.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "\x01\x00" + "\xfe\xff" + "\x02\x01"
+	if string(data) != expect {
+		t.Fatalf("expected %q in numbers.dat; got %q", expect, data)
+	}
+	// id 2, name 6, price 8, color 4, tags 32, codes 2, active 1.
+	if data, _ = fs.ReadFile(files, "items.dat"); len(data) != 3*55 {
+		t.Fatalf("expected 3 records of 55 bytes in items.dat; got %d bytes", len(data))
+	}
+	if record := string(data[:16]); record != "\x01\x00\x05item1\x00\x00\x00\x00\x00\x00\xf8\x3f" {
+		t.Fatalf("unexpected layout of the first record: %q", record)
+	}
+}
+
+func TestInterpreterTypedFileErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		code    int
+	}{{
+		program: `PROGRAM test; VAR f : FILE OF ^INTEGER; BEGIN END.`,
+	}, {
+		program: `PROGRAM test; VAR f : FILE OF TEXT; BEGIN END.`,
+	}, {
+		program: `PROGRAM test; VAR f : FILE OF INTEGER; BEGIN Write(f, 'a') END.`,
+	}, {
+		program: `PROGRAM test; VAR f : FILE OF INTEGER; BEGIN Write(f, 1:3) END.`,
+	}, {
+		program: `PROGRAM test; VAR f : FILE OF INTEGER; BEGIN WriteLn(f, 1) END.`,
+	}, {
+		program: `PROGRAM test; VAR f : FILE OF INTEGER; c : CHAR; BEGIN Read(f, c) END.`,
+	}, {
+		program: `PROGRAM test; VAR f : FILE OF INTEGER; BEGIN Append(f) END.`,
+	}, {
+		program: `PROGRAM test; VAR f : TEXT; BEGIN Seek(f, 1) END.`,
+	}, {
+		program: `PROGRAM test; VAR f : FILE OF INTEGER; b : BOOLEAN; BEGIN b := Eoln(f) END.`,
+	}, {
+		program: `PROGRAM test; VAR f : FILE OF INTEGER; n : INTEGER; BEGIN n := FilePos(f) END.`,
+		code:    errCodeNotOpen,
+	}, {
+		program: `PROGRAM test; VAR f : FILE OF INTEGER; BEGIN Assign(f, 'a.dat'); Reset(f) END.`,
+		code:    errCodeFileNotFound,
+	}, {
+		program: `PROGRAM test; VAR f : FILE OF INTEGER; n : INTEGER; BEGIN Assign(f, 'a.dat'); Rewrite(f); Write(f, 1); Read(f, n) END.`,
+		code:    errCodeDiskRead,
+	}, {
+		program: `PROGRAM test; VAR f : FILE OF INTEGER; BEGIN Assign(f, 'a.dat'); Rewrite(f); Seek(f, 1) END.`,
+		code:    errCodeDiskRead,
+	}, {
+		program: `PROGRAM test; VAR f : FILE OF BYTE; BEGIN Assign(f, 'a.dat'); Rewrite(f); Write(f, 300) END.`,
+	}, {
+		program: `{$R+} PROGRAM test; VAR f : FILE OF BYTE; n : INTEGER; BEGIN n := 300; Assign(f, 'a.dat'); Rewrite(f); Write(f, n) END.`,
+		code:    errCodeRangeCheck,
+	}}
+	for _, test := range tests {
+		i := newInterpreter(test.program)
+		i.files = newMemFS()
+		err := i.walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		runtimeErr, ok := err.(*errRuntime)
+		if ok != (test.code != 0) || ok && runtimeErr.code != test.code {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterMath(t *testing.T) {
+	program := `
+PROGRAM math;
+CONST
+	squared = Sqr(-7);
+VAR
+	absint, sqrint, truncated, rounded, roundedneg, floored, ceiled, minint, maxint : INTEGER;
+	absreal, sqrreal, root, sine, cosine, tangent, arctangent, e, logarithm, pow, maxreal : REAL;
+	odd3, odd4, oddneg : BOOLEAN;
+BEGIN
+	absint := Abs(-5);
+	sqrint := squared + Sqr(3);
+	absreal := Abs(-2.5);
+	sqrreal := Sqr(1.5);
+	root := Sqrt(16);
+	sine := Sin(0);
+	cosine := Cos(0);
+	tangent := Tan(0.0);
+	arctangent := ArcTan(0);
+	e := Exp(0);
+	logarithm := Ln(1);
+	truncated := Trunc(-3.7);
+	rounded := Round(2.5);
+	roundedneg := Round(-2.5);
+	floored := Floor(-3.2);
+	ceiled := Ceil(3.2);
+	odd3 := Odd(3);
+	odd4 := Odd(4);
+	oddneg := Odd(-3);
+	pow := Power(2, 10) + Power(-2, 3);
+	minint := Min(3, -4);
+	maxint := Max(3, -4);
+	maxreal := Max(1, 1.5) + Min(2, 2.5)
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"absint":     5,
+		"sqrint":     58,
+		"absreal":    2.5,
+		"sqrreal":    2.25,
+		"root":       4.0,
+		"sine":       0.0,
+		"cosine":     1.0,
+		"tangent":    0.0,
+		"arctangent": 0.0,
+		"e":          1.0,
+		"logarithm":  0.0,
+		"truncated":  -3,
+		"rounded":    3,
+		"roundedneg": -3,
+		"floored":    -4,
+		"ceiled":     4,
+		"odd3":       true,
+		"odd4":       false,
+		"oddneg":     true,
+		"pow":        1016.0,
+		"minint":     -4,
+		"maxint":     3,
+		"maxreal":    3.5,
+	})
+}
+
+func TestInterpreterMathErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		code    int
+	}{{
+		program: `PROGRAM test; VAR n : INTEGER; BEGIN n := Sqrt(4) END.`,
+	}, {
+		program: `PROGRAM test; VAR n : INTEGER; BEGIN n := Abs('a') END.`,
+	}, {
+		program: `PROGRAM test; VAR n : INTEGER; BEGIN n := Sqr(1.5) END.`,
+	}, {
+		program: `PROGRAM test; VAR b : BOOLEAN; BEGIN b := Odd(1.5) END.`,
+	}, {
+		program: `PROGRAM test; VAR n : INTEGER; BEGIN n := Max(1, 2.5) END.`,
+	}, {
+		program: `PROGRAM test; VAR x : REAL; BEGIN x := Power(2) END.`,
+	}, {
+		program: `PROGRAM test; CONST x = Sqrt(-1); BEGIN END.`,
+	}, {
+		program: `PROGRAM test; VAR x : REAL; BEGIN x := -1; x := Sqrt(x) END.`,
+		code:    errCodeInvalidFloat,
+	}, {
+		program: `PROGRAM test; VAR x : REAL; BEGIN x := 0; x := Ln(x) END.`,
+		code:    errCodeInvalidFloat,
+	}, {
+		program: `PROGRAM test; VAR x : REAL; BEGIN x := 1000; x := Exp(x) END.`,
+		code:    errCodeFloatOverflow,
+	}, {
+		program: `PROGRAM test; VAR x : REAL; BEGIN x := Power(-8, 1 / 3) END.`,
+		code:    errCodeInvalidFloat,
+	}, {
+		program: `PROGRAM test; VAR x : REAL; n : INTEGER; BEGIN x := 1000000.0 * 1000000.0 * 10000000.0; n := Round(x) END.`,
+		code:    errCodeInvalidFloat,
+	}}
+	for _, test := range tests {
+		err := newInterpreter(test.program).walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		runtimeErr, ok := err.(*errRuntime)
+		if ok != (test.code != 0) || ok && runtimeErr.code != test.code {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterIntegerOperators(t *testing.T) {
+	program := `
+PROGRAM operators;
+VAR
+	m1, m2, m3, m4, signed, andint, orint, xorint, notint, shl1, shr1, shrneg, precedence : INTEGER;
+	hash : INT64;
+	xorbool, shortcut : BOOLEAN;
+	c : CHAR;
+BEGIN
+	m1 := 7 MOD 3;
+	m2 := (-7) MOD 3;
+	m3 := 6 MOD 3;
+	m4 := (-1) MOD 5;
+	signed := -7 MOD 3;
+	andint := 12 AND 10;
+	orint := 12 OR 10;
+	xorint := 12 XOR 10;
+	notint := NOT 0;
+	shl1 := 1 SHL 10;
+	shr1 := 1024 SHR 3;
+	shrneg := (-1) SHR 60;
+	precedence := 1 + 2 SHL 3 XOR 1;
+	xorbool := TRUE XOR FALSE;
+	shortcut := (1 = 2) AND (1 DIV 0 = 0);
+	hash := 5381;
+	FOR c := 'a' TO 'c' DO
+		hash := ((hash SHL 5) + hash) XOR Ord(c)
+END.
+`
+	i := newInterpreter(program)
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"m1":         1,
+		"m2":         2,
+		"m3":         0,
+		"m4":         4,
+		"signed":     -1,
+		"andint":     8,
+		"orint":      14,
+		"xorint":     6,
+		"notint":     -1,
+		"shl1":       1024,
+		"shr1":       128,
+		"shrneg":     15,
+		"precedence": 16,
+		"xorbool":    true,
+		"shortcut":   false,
+		"hash":       193409669,
+	})
+}
+
+func TestInterpreterIntegerOperatorErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		code    int
+	}{{
+		program: `PROGRAM test; BEGIN a := 1.5 MOD 2 END.`,
+	}, {
+		program: `PROGRAM test; BEGIN a := 1 SHL 1.5 END.`,
+	}, {
+		program: `PROGRAM test; BEGIN a := TRUE XOR 1 END.`,
+	}, {
+		program: `PROGRAM test; BEGIN a := 1 AND TRUE END.`,
+	}, {
+		program: `PROGRAM test; VAR n : INTEGER; BEGIN n := 0; a := 1 MOD n END.`,
+		code:    errCodeDivisionByZero,
+	}, {
+		program: `PROGRAM test; VAR n : INTEGER; BEGIN n := -3; a := 1 MOD n END.`,
+	}}
+	for _, test := range tests {
+		err := newInterpreter(test.program).walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		if runtimeErr, ok := err.(*errRuntime); test.code != 0 && (!ok || runtimeErr.code != test.code) {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterIntegerTypes(t *testing.T) {
+	tests := []struct {
+		program string
+		expect  map[string]interface{}
+	}{{
+		program: `
+PROGRAM wrap;
+VAR
+	s : SHORTINT;
+	b, nb : BYTE;
+	w : WORD;
+	c : CARDINAL;
+	big : INT64;
+	i : INTEGER;
+	l : LONGINT;
+BEGIN
+	s := 127;
+	s := s + 1;
+	b := 255;
+	b := b + 1;
+	nb := NOT b;
+	w := 0;
+	w := w - 1;
+	c := 0;
+	c := c - 1;
+	big := 9223372036854775807;
+	big := big + 1;
+	i := 200;
+	l := i * i;
+	i := i * i
+END.
+`,
+		expect: map[string]interface{}{
+			"s": -128, "b": 0, "nb": 255, "w": 65535, "c": 4294967295,
+			"big": -9223372036854775807 - 1, "l": 40000, "i": -25536,
+		},
+	}, {
+		program: `{$MODE TP}
+PROGRAM turbo;
+VAR
+	i : INTEGER;
+	l, shr1, wide : LONGINT;
+BEGIN
+	i := 200;
+	l := i * i;
+	shr1 := (-1) SHR 1;
+	wide := i * 40000
+END.
+`,
+		expect: map[string]interface{}{"l": -25536, "shr1": 32767, "wide": 8000000},
+	}, {
+		program: `{$MODE OBJFPC}
+PROGRAM objfpc;
+VAR
+	i, j : INTEGER;
+BEGIN
+	i := 40000;
+	j := 2147483647;
+	j := j + 1
+END.
+`,
+		expect: map[string]interface{}{"i": 40000, "j": -2147483648},
+	}}
+
+	for _, test := range tests {
+		i := newInterpreter(test.program)
+		if err := i.walk(); err != nil {
+			t.Fatal(err)
+		}
+		checkGlobalScope(t, i, test.expect)
+	}
+}
+
+func TestInterpreterIntegerTypeErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		code    int
+	}{{
+		program: `PROGRAM test; CONST c : BYTE = 256; BEGIN END.`,
+	}, {
+		program: `PROGRAM test; VAR a : ARRAY[INT64] OF BYTE; BEGIN END.`,
+	}, {
+		program: `{$MODE TP}{$Q+} PROGRAM test; VAR i : INTEGER; BEGIN i := 32767; i := i + 1 END.`,
+		code:    errCodeOverflow,
+	}, {
+		program: `{$MODE TP}{$Q+} PROGRAM test; VAR i : INTEGER; BEGIN i := -32768; i := -i END.`,
+		code:    errCodeOverflow,
+	}, {
+		program: `{$OVERFLOWCHECKS ON} PROGRAM test; VAR i : INT64; BEGIN i := 4611686018427387904; i := i * 2 END.`,
+		code:    errCodeOverflow,
+	}, {
+		program: `{$R+} PROGRAM test; VAR i : INTEGER; BEGIN i := 32767; i := i + 1 END.`,
+		code:    errCodeRangeCheck,
+	}, {
+		program: `{$R+} PROGRAM test; VAR b : BYTE; n : INTEGER; BEGIN n := -1; b := n END.`,
+		code:    errCodeRangeCheck,
+	}}
+	for _, test := range tests {
+		err := newInterpreter(test.program).walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		if runtimeErr, ok := err.(*errRuntime); ok != (test.code != 0) || ok && runtimeErr.code != test.code {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterBigIntegers(t *testing.T) {
+	program := `
+PROGRAM bigIntegers;
+VAR
+	f, big, quotient, remainder, shifted, negated : INTEGER;
+	small : LONGINT;
+	less : BOOLEAN;
+	x : REAL;
+
+FUNCTION Factorial(n : INTEGER) : INTEGER;
+BEGIN
+	IF n <= 1 THEN
+		Factorial := 1
+	ELSE
+		Factorial := n * Factorial(n - 1)
+END;
+
+BEGIN
+	f := Factorial(30);
+	big := 100000000000000000000;
+	quotient := f DIV big;
+	remainder := (-f) MOD 1000007;
+	shifted := 1 SHL 100 SHR 98;
+	negated := -(f - f + 9223372036854775807 + 1);
+	small := big;
+	less := big < f;
+	x := big;
+	WriteLn(f);
+	WriteLn(f:40, Sqr(big) DIV f)
+END.
+`
+	var output strings.Builder
+	i := newInterpreter(program)
+	i.options.bigIntegers = true
+	i.output = &output
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	f, _ := new(big.Int).SetString("265252859812191058636308480000000", 10)
+	remainder := new(big.Int).Mod(new(big.Int).Neg(f), big.NewInt(1000007))
+	if got, _ := i.globalScope.get("f"); got.(*big.Int).Cmp(f) != 0 {
+		t.Fatalf("expected to get %v for f; got %v", f, got)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"quotient":  int(2652528598121),
+		"remainder": int(remainder.Int64()),
+		"shifted":   4,
+		"negated":   math.MinInt64,
+		"small":     1661992960,
+		"less":      true,
+		"x":         1e20,
+	})
+	expect := "265252859812191058636308480000000\n       265252859812191058636308480000000" + "37699876\n"
+	if output.String() != expect {
+		t.Fatalf("expected %q; got %q", expect, output.String())
+	}
+}
+
+func TestInterpreterBigIntegerErrors(t *testing.T) {
+	tests := []struct {
+		program     string
+		bigIntegers bool
+		code        int
+	}{{
+		program: `PROGRAM test; VAR i : INT64; BEGIN i := 9223372036854775808 END.`,
+	}, {
+		program:     `PROGRAM test; VAR a : ARRAY[INTEGER] OF BYTE; BEGIN END.`,
+		bigIntegers: true,
+	}, {
+		program:     `PROGRAM test; VAR f : FILE OF INTEGER; BEGIN END.`,
+		bigIntegers: true,
+	}, {
+		program:     `PROGRAM test; VAR i : INTEGER; BEGIN i := 100000000000000000000 DIV 0 END.`,
+		bigIntegers: true,
+		code:        errCodeDivisionByZero,
+	}, {
+		program:     `{$R+} PROGRAM test; VAR i : INT64; BEGIN i := 100000000000000000000 END.`,
+		bigIntegers: true,
+		code:        errCodeRangeCheck,
+	}}
+	for _, test := range tests {
+		i := newInterpreter(test.program)
+		i.options.bigIntegers = test.bigIntegers
+		err := i.walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		if runtimeErr, ok := err.(*errRuntime); ok != (test.code != 0) || ok && runtimeErr.code != test.code {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterRealTypes(t *testing.T) {
+	program := `
+PROGRAM reals;
+VAR
+	s, product : SINGLE;
+	d, third, mixed : DOUBLE;
+	e : EXTENDED;
+	c, even : COMP;
+	f : FILE OF SINGLE;
+	g : FILE OF COMP;
+BEGIN
+	s := 0.1;
+	d := s;
+	product := s * 3;
+	mixed := s * 0.5;
+	third := 1 / 3;
+	e := third;
+	c := 2.4 + 1;
+	even := 2.5;
+	Assign(f, 'single.dat');
+	Rewrite(f);
+	Write(f, 1.5);
+	Close(f);
+	Assign(g, 'comp.dat');
+	Rewrite(g);
+	Write(g, c, -c);
+	Close(g)
+END.
+`
+	files := newMemFS()
+	i := newInterpreter(program)
+	i.files = files
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	single := float64(float32(0.1))
+	checkGlobalScope(t, i, map[string]interface{}{
+		"s":       single,
+		"d":       single,
+		"product": float64(float32(single * 3)),
+		"mixed":   single * 0.5,
+		"third":   1.0 / 3,
+		"e":       1.0 / 3,
+		"c":       3.0,
+		"even":    2.0,
+	})
+	for name, expect := range map[string]string{
+		"single.dat": "\x00\x00\xc0\x3f",
+		"comp.dat":   "\x03\x00\x00\x00\x00\x00\x00\x00" + "\xfd\xff\xff\xff\xff\xff\xff\xff",
+	} {
+		if data, _ := fs.ReadFile(files, name); string(data) != expect {
+			t.Fatalf("expected %q in %v; got %q", expect, name, data)
+		}
+	}
+}
+
+func TestInterpreterDecimalTypes(t *testing.T) {
+	program := `
+PROGRAM fees;
+VAR
+	price, fee, total, share, change : CURRENCY;
+	rate, exact, third, inverse, long : DECIMAL;
+	r : REAL;
+	rounded, truncated, floored : INT64;
+	f : FILE OF CURRENCY;
+BEGIN
+	price := 19.99;
+	fee := price * 0.015;
+	total := price + fee;
+	share := 100 / 3;
+	change := -total + 50;
+	rate := 0.1;
+	exact := rate + 0.2;
+	third := exact / 3;
+	inverse := 1 / exact;
+	long := 0.1234567890123456789012345;
+	r := total;
+	rounded := Round(price);
+	truncated := Trunc(change);
+	floored := Floor(-fee);
+	WriteLn(total, ' ', third, ' ', long);
+	WriteLn(fee:8:2, exact:6:3, Abs(-fee):7);
+	Assign(f, 'currency.dat');
+	Rewrite(f);
+	Write(f, total);
+	Close(f)
+END.
+`
+	var output strings.Builder
+	files := newMemFS()
+	i := newInterpreter(program)
+	i.files = files
+	i.output = &output
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"price":     currency(199900),
+		"fee":       currency(2998),
+		"total":     currency(202898),
+		"share":     currency(333333),
+		"change":    currency(297102),
+		"r":         20.2898,
+		"rounded":   20,
+		"truncated": 29,
+		"floored":   -1,
+	})
+	for name, expect := range map[string]string{
+		"exact":   "0.3",
+		"third":   "0.1",
+		"inverse": "3.3333333333333333333333333333",
+		"long":    "0.1234567890123456789012345",
+	} {
+		if got, _ := i.globalScope.get(name); got.(decimal).String() != expect {
+			t.Fatalf("expected to get %v for %v; got %v", expect, name, got)
+		}
+	}
+	expect := "20.2898 0.1 0.1234567890123456789012345\n    0.30 0.300 0.2998\n"
+	if output.String() != expect {
+		t.Fatalf("expected %q; got %q", expect, output.String())
+	}
+	if data, _ := fs.ReadFile(files, "currency.dat"); string(data) != "\x92\x18\x03\x00\x00\x00\x00\x00" {
+		t.Fatalf("unexpected contents of currency.dat: %q", data)
+	}
+}
+
+func TestInterpreterLongRealLiterals(t *testing.T) {
+	program := `
+PROGRAM literals;
+CONST
+	Pi = 3.14159265358979323846;
+	Tau : DECIMAL = 6.28318530717958647692;
+VAR
+	r, twice : REAL;
+	d : DECIMAL = -2.71828182845904523536;
+	c : CURRENCY;
+BEGIN
+	r := 2;
+	twice := r * Pi;
+	c := 0.12345678901234567890;
+	WriteLn(Pi, ' ', twice:0:4, ' ', r * 1.00000000000000000001);
+	WriteLn(Tau, ' ', d, ' ', c)
+END.
+`
+	var output strings.Builder
+	i := newInterpreter(program)
+	i.output = &output
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	expect := " 3.1415926536E+00 6.2832  2.0000000000E+00\n6.28318530717958647692 -2.71828182845904523536 0.1235\n"
+	if output.String() != expect {
+		t.Fatalf("expected %q; got %q", expect, output.String())
+	}
+}
+
+func TestInterpreterDecimalTypeErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		code    int
+	}{{
+		program: `PROGRAM test; VAR c : CURRENCY; i : INTEGER; BEGIN i := c END.`,
+	}, {
+		program: `PROGRAM test; VAR c : CURRENCY; i : INTEGER; BEGIN i := c MOD 2 END.`,
+	}, {
+		program: `PROGRAM test; VAR f : FILE OF DECIMAL; BEGIN END.`,
+	}, {
+		program: "PROGRAM test; VAR x : REAL; BEGIN x := 1" + strings.Repeat("0", 400) + ".0 END.",
+	}, {
+		program: `PROGRAM test; VAR c : CURRENCY; BEGIN c := 1.5 / c END.`,
+		code:    errCodeDivisionByZero,
+	}, {
+		program: `PROGRAM test; VAR d : DECIMAL; BEGIN d := 0; d := 1 / d END.`,
+		code:    errCodeDivisionByZero,
+	}, {
+		program: `PROGRAM test; VAR c : CURRENCY; BEGIN c := 900000000000000; c := c * 100 END.`,
+		code:    errCodeInvalidFloat,
+	}}
+	for _, test := range tests {
+		err := newInterpreter(test.program).walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		if runtimeErr, ok := err.(*errRuntime); ok != (test.code != 0) || ok && runtimeErr.code != test.code {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterComplex(t *testing.T) {
+	program := `
+PROGRAM signals;
+CONST
+	i = Cmplx(0, 1);
+VAR
+	z, w, sum, product, quotient, mixed, root, unit : COMPLEX;
+	magnitude, angle, x, y : REAL;
+	equal : BOOLEAN;
+	f : FILE OF COMPLEX;
+BEGIN
+	z := Cmplx(3, 4);
+	w := 2;
+	sum := z + 1.5;
+	product := z * i;
+	quotient := z / Cmplx(0, 2);
+	mixed := -z - 1;
+	root := Sqrt(Cmplx(-4, 0));
+	unit := Polar(2, 0);
+	magnitude := Abs(z);
+	angle := Arg(i);
+	x := Re(z);
+	y := Im(Sqr(z));
+	equal := (w = 2) AND (z <> w);
+	Assign(f, 'complex.dat');
+	Rewrite(f);
+	Write(f, i);
+	Close(f)
+END.
+`
+	files := newMemFS()
+	i := newInterpreter(program)
+	i.files = files
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"z":         complex(3, 4),
+		"w":         complex(2, 0),
+		"sum":       complex(4.5, 4),
+		"product":   complex(-4, 3),
+		"quotient":  complex(2, -1.5),
+		"mixed":     complex(-4, -4),
+		"root":      complex(0, 2),
+		"unit":      complex(2, 0),
+		"magnitude": 5.0,
+		"angle":     math.Pi / 2,
+		"x":         3.0,
+		"y":         24.0,
+		"equal":     true,
+	})
+	expect := "\x00\x00\x00\x00\x00\x00\x00\x00" + "\x00\x00\x00\x00\x00\x00\xf0\x3f"
+	if data, _ := fs.ReadFile(files, "complex.dat"); string(data) != expect {
+		t.Fatalf("expected %q in complex.dat; got %q", expect, data)
+	}
+}
+
+func TestInterpreterComplexErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		code    int
+	}{{
+		program: `PROGRAM test; VAR z : COMPLEX; b : BOOLEAN; BEGIN b := z < z END.`,
+	}, {
+		program: `PROGRAM test; VAR z : COMPLEX; x : REAL; BEGIN x := z END.`,
+	}, {
+		program: `PROGRAM test; VAR z : COMPLEX; BEGIN WriteLn(z) END.`,
+	}, {
+		program: `PROGRAM test; VAR z : COMPLEX; BEGIN z := Cmplx(z, 1) END.`,
+	}, {
+		program: `PROGRAM test; VAR z : COMPLEX; BEGIN z := Cmplx(1, 1) / z END.`,
+		code:    errCodeDivisionByZero,
+	}, {
+		program: `PROGRAM test; VAR z : COMPLEX; BEGIN z := Ln(z) END.`,
+		code:    errCodeInvalidFloat,
+	}}
+	for _, test := range tests {
+		err := newInterpreter(test.program).walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		if runtimeErr, ok := err.(*errRuntime); ok != (test.code != 0) || ok && runtimeErr.code != test.code {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
+
+func TestInterpreterMaskedFloatExceptions(t *testing.T) {
+	program := `
+PROGRAM masked;
+VAR
+	x, y, z : REAL;
+	s : SINGLE;
+	equal, notEqual : BOOLEAN;
+BEGIN
+	x := 1 / 0;
+	y := -1 / 0.0;
+	z := 0 / 0;
+	s := 1000000000.0;
+	s := s * s * s * s * s;
+	equal := z = z;
+	notEqual := z <> z;
+	WriteLn(x);
+	WriteLn(y:8, z:5:2);
+	WriteLn(Sqrt(-1), Ln(0));
+	WriteLn(x - x, 1.0)
+END.
+`
+	var output strings.Builder
+	i := newInterpreter(program)
+	i.options.fpuMask = fpuAllExceptions
+	i.output = &output
+	if err := i.walk(); err != nil {
+		t.Fatal(err)
+	}
+	checkGlobalScope(t, i, map[string]interface{}{
+		"x":        math.Inf(1),
+		"y":        math.Inf(-1),
+		"s":        math.Inf(1),
+		"equal":    false,
+		"notequal": true,
+	})
+	expect := "             +Inf\n    -Inf  Nan\n              Nan             -Inf\n              Nan 1.0000000000E+00\n"
+	if output.String() != expect {
+		t.Fatalf("expected %q; got %q", expect, output.String())
+	}
+}
+
+func TestInterpreterFloatExceptions(t *testing.T) {
+	tests := []struct {
+		program string
+		code    int
+	}{{
+		program: `PROGRAM test; VAR x : REAL; BEGIN x := 1 / 0 END.`,
+		code:    errCodeDivisionByZero,
+	}, {
+		program: `PROGRAM test; VAR x : REAL; BEGIN x := 0; x := x / x END.`,
+		code:    errCodeInvalidFloat,
+	}, {
+		program: `PROGRAM test; VAR s : SINGLE; BEGIN s := 1000000000.0; s := s * s * s * s * s END.`,
+		code:    errCodeFloatOverflow,
+	}, {
+		program: `PROGRAM test; VAR x : REAL; BEGIN x := Exp(1000) END.`,
+		code:    errCodeFloatOverflow,
+	}}
+	for _, test := range tests {
+		err := newInterpreter(test.program).walk()
+		if err == nil {
+			t.Fatalf("expected an error for %q", test.program)
+		}
+		if runtimeErr, ok := err.(*errRuntime); ok != (test.code != 0) || ok && runtimeErr.code != test.code {
+			t.Fatalf("unexpected error for %q: %v", test.program, err)
+		}
+	}
+}
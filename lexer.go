@@ -1,6 +1,7 @@
 package go_pascal
 
 import (
+	"math/big"
 	"strconv"
 	"strings"
 )
@@ -8,13 +9,25 @@ import (
 type lexer struct {
 	input string
 	pos   int
+	// line is the current line, and lineStart the position where it starts.
+	line, lineStart int
+	// options is updated by the compiler directives in the input.
+	options *options
 }
 
 func newLexer(input string) *lexer {
-	return &lexer{input: input}
+	return &lexer{
+		input:   input,
+		line:    1,
+		options: newOptions(),
+	}
 }
 
 func (l *lexer) advance() {
+	if l.currentChar() == '\n' {
+		l.line++
+		l.lineStart = l.pos + 1
+	}
 	l.pos++
 }
 
@@ -39,7 +52,7 @@ func (l *lexer) getIDToken() *token {
 	}
 	id := strings.ToLower(l.input[startIndex:l.pos])
 	if t, ok := keywordToToken[id]; ok {
-		return t
+		return newToken(t.tokenType, t.value)
 	}
 	return newToken(tokenTypeID, id)
 }
@@ -50,10 +63,15 @@ func (l *lexer) getNumberToken() *token {
 	for l.pos < len(l.input) && isDigit(l.currentChar()) {
 		l.advance()
 	}
-	if l.pos >= len(l.input) || l.currentChar() != '.' {
-		value, err := strconv.ParseInt(l.input[startIndex:l.pos], 10, 64)
+	// 1..5 is a range of integers, not the real number 1. followed by .5.
+	if l.pos >= len(l.input) || l.currentChar() != '.' || l.peek() == '.' {
+		digits := l.input[startIndex:l.pos]
+		value, err := strconv.ParseInt(digits, 10, 64)
 		if err != nil {
-			panic(err)
+			// Literals too large for an int are big constants, which are
+			// only valid if INTEGER has no bounds.
+			b, _ := new(big.Int).SetString(digits, 10)
+			return newToken(tokenTypeIntegerConst, b)
 		}
 		return newToken(tokenTypeIntegerConst, int(value))
 	}
@@ -62,13 +80,57 @@ func (l *lexer) getNumberToken() *token {
 	for l.pos < len(l.input) && isDigit(l.currentChar()) {
 		l.advance()
 	}
-	value, err := strconv.ParseFloat(l.input[startIndex:l.pos], 64)
-	if err != nil {
-		panic(err)
+	digits := l.input[startIndex:l.pos]
+	// A literal with more digits than a real holds, i.e. one that does not
+	// read back from the real, keeps its exact digits for CURRENCY and
+	// DECIMAL variables. So does one too large for a real.
+	value, err := strconv.ParseFloat(digits, 64)
+	if d, _ := parseDecimal(digits); err != nil || toDecimal(value).cmp(d) != 0 {
+		return newToken(tokenTypeRealConst, d)
 	}
 	return newToken(tokenTypeRealConst, value)
 }
 
+// getStringToken reads a string made of quoted strings and character codes
+// such as 'abc'#13#10. It returns an unknown token if a quoted string is not
+// terminated on the same line. A quote inside a quoted string is doubled.
+func (l *lexer) getStringToken() *token {
+	var value []byte
+	for {
+		switch l.currentChar() {
+		case '\'':
+			l.advance()
+			for {
+				ch := l.currentChar()
+				if ch == 0 || ch == '\n' {
+					return newToken(tokenTypeUnknown, byte('\''))
+				}
+				l.advance()
+				if ch == '\'' {
+					if l.currentChar() != '\'' {
+						break
+					}
+					l.advance()
+				}
+				value = append(value, ch)
+			}
+		case '#':
+			l.advance()
+			startIndex := l.pos
+			for isDigit(l.currentChar()) {
+				l.advance()
+			}
+			code, err := strconv.Atoi(l.input[startIndex:l.pos])
+			if err != nil || code > 255 {
+				return newToken(tokenTypeUnknown, byte('#'))
+			}
+			value = append(value, byte(code))
+		default:
+			return newToken(tokenTypeStringConst, string(value))
+		}
+	}
+}
+
 func (l *lexer) getAllTokens() []*token {
 	var result []*token
 	for {
@@ -83,19 +145,40 @@ func (l *lexer) getAllTokens() []*token {
 
 func (l *lexer) getNextToken() *token {
 	l.skipWhitespace()
+	pos := position{line: l.line, column: l.pos - l.lineStart + 1}
 	if l.pos >= len(l.input) {
-		return newToken(tokenTypeEOF, nil)
+		t := newToken(tokenTypeEOF, nil)
+		t.pos = pos
+		return t
 	}
 	var t *token
 	ch := l.currentChar()
 	switch {
 	case ch == '_' || isAlpha(ch):
 		t = l.getIDToken()
-	case isDigit(ch) || ch == '.' && isDigit(l.peek()):
+	case isDigit(ch):
 		t = l.getNumberToken()
+	case ch == '\'' || ch == '#':
+		t = l.getStringToken()
+	case ch == '.' && l.peek() == '.':
+		l.advance()
+		l.advance()
+		t = newToken(tokenTypeRange, nil)
 	case ch == '.':
 		l.advance()
 		t = newToken(tokenTypeDot, nil)
+	case ch == '^':
+		l.advance()
+		t = newToken(tokenTypeCaret, nil)
+	case ch == '@':
+		l.advance()
+		t = newToken(tokenTypeAt, nil)
+	case ch == '[':
+		l.advance()
+		t = newToken(tokenTypeLBracket, nil)
+	case ch == ']':
+		l.advance()
+		t = newToken(tokenTypeRBracket, nil)
 	case ch == ',':
 		l.advance()
 		t = newToken(tokenTypeComma, nil)
@@ -109,6 +192,27 @@ func (l *lexer) getNextToken() *token {
 	case ch == ':' && l.peek() != '=':
 		l.advance()
 		t = newToken(tokenTypeColon, nil)
+	case ch == '=':
+		l.advance()
+		t = newToken(tokenTypeEqual, nil)
+	case ch == '<' && l.peek() == '>':
+		l.advance()
+		l.advance()
+		t = newToken(tokenTypeNotEqual, nil)
+	case ch == '<' && l.peek() == '=':
+		l.advance()
+		l.advance()
+		t = newToken(tokenTypeLessEqual, nil)
+	case ch == '<':
+		l.advance()
+		t = newToken(tokenTypeLess, nil)
+	case ch == '>' && l.peek() == '=':
+		l.advance()
+		l.advance()
+		t = newToken(tokenTypeGreaterEqual, nil)
+	case ch == '>':
+		l.advance()
+		t = newToken(tokenTypeGreater, nil)
 	case ch == '+':
 		l.advance()
 		t = newToken(tokenTypePlus, nil)
@@ -135,21 +239,29 @@ func (l *lexer) getNextToken() *token {
 		t = newToken(tokenTypeUnknown, ch)
 	}
 
+	t.pos = pos
 	return t
 }
 
+// skipComment skips a comment. Comments starting with a dollar sign are
+// compiler directives.
 func (l *lexer) skipComment() {
+	startIndex := l.pos + 1
 	for l.pos < len(l.input) && l.currentChar() != '}' {
 		l.advance()
 	}
+	comment := l.input[startIndex:l.pos]
 	if l.currentChar() == '}' {
 		l.advance()
 	}
+	if strings.HasPrefix(comment, "$") {
+		l.options.applyDirective(comment[1:])
+	}
 }
 
 func (l *lexer) skipWhitespace() {
 	for l.pos < len(l.input) && isWhitespace(l.input[l.pos]) {
-		l.pos++
+		l.advance()
 	}
 }
 
@@ -1,6 +1,7 @@
 package go_pascal
 
 import (
+	"math/big"
 	"reflect"
 	"testing"
 )
@@ -21,7 +22,8 @@ func TestLexer(t *testing.T) {
 			newToken(tokenTypeBegin, nil),
 			newToken(tokenTypeID, "a"),
 			newToken(tokenTypeAssign, nil),
-			newToken(tokenTypeRealConst, .5),
+			newToken(tokenTypeDot, nil),
+			newToken(tokenTypeIntegerConst, 5),
 			newToken(tokenTypeSemi, nil),
 			newToken(tokenTypeID, "x"),
 			newToken(tokenTypeAssign, nil),
@@ -56,13 +58,115 @@ END.
 			newToken(tokenTypeEnd, nil),
 			newToken(tokenTypeDot, nil),
 		},
+	}, {
+		program: `IF (a <> b) AND NOT (c >= 1) OR x <= y THEN c := a = TRUE ELSE b := a > b`,
+		tokens: []*token{
+			newToken(tokenTypeIf, nil),
+			newToken(tokenTypeLParen, nil),
+			newToken(tokenTypeID, "a"),
+			newToken(tokenTypeNotEqual, nil),
+			newToken(tokenTypeID, "b"),
+			newToken(tokenTypeRParen, nil),
+			newToken(tokenTypeAnd, nil),
+			newToken(tokenTypeNot, nil),
+			newToken(tokenTypeLParen, nil),
+			newToken(tokenTypeID, "c"),
+			newToken(tokenTypeGreaterEqual, nil),
+			newToken(tokenTypeIntegerConst, 1),
+			newToken(tokenTypeRParen, nil),
+			newToken(tokenTypeOr, nil),
+			newToken(tokenTypeID, "x"),
+			newToken(tokenTypeLessEqual, nil),
+			newToken(tokenTypeID, "y"),
+			newToken(tokenTypeThen, nil),
+			newToken(tokenTypeID, "c"),
+			newToken(tokenTypeAssign, nil),
+			newToken(tokenTypeID, "a"),
+			newToken(tokenTypeEqual, nil),
+			newToken(tokenTypeBooleanConst, true),
+			newToken(tokenTypeElse, nil),
+			newToken(tokenTypeID, "b"),
+			newToken(tokenTypeAssign, nil),
+			newToken(tokenTypeID, "a"),
+			newToken(tokenTypeGreater, nil),
+			newToken(tokenTypeID, "b"),
+		},
+	}, {
+		program: `CASE x OF 1..5, 7: ; 1.5 END`,
+		tokens: []*token{
+			newToken(tokenTypeCase, nil),
+			newToken(tokenTypeID, "x"),
+			newToken(tokenTypeOf, nil),
+			newToken(tokenTypeIntegerConst, 1),
+			newToken(tokenTypeRange, nil),
+			newToken(tokenTypeIntegerConst, 5),
+			newToken(tokenTypeComma, nil),
+			newToken(tokenTypeIntegerConst, 7),
+			newToken(tokenTypeColon, nil),
+			newToken(tokenTypeSemi, nil),
+			newToken(tokenTypeRealConst, 1.5),
+			newToken(tokenTypeEnd, nil),
+		},
+	}, {
+		program: `s := 'It''s'#65 + '' + #13#10'x'`,
+		tokens: []*token{
+			newToken(tokenTypeID, "s"),
+			newToken(tokenTypeAssign, nil),
+			newToken(tokenTypeStringConst, "It'sA"),
+			newToken(tokenTypePlus, nil),
+			newToken(tokenTypeStringConst, ""),
+			newToken(tokenTypePlus, nil),
+			newToken(tokenTypeStringConst, "\r\nx"),
+		},
+	}, {
+		program: `n := 9223372036854775808`,
+		tokens: []*token{
+			newToken(tokenTypeID, "n"),
+			newToken(tokenTypeAssign, nil),
+			newToken(tokenTypeIntegerConst, new(big.Int).Lsh(big.NewInt(1), 63)),
+		},
+	}, {
+		program: `p := @Compare`,
+		tokens: []*token{
+			newToken(tokenTypeID, "p"),
+			newToken(tokenTypeAssign, nil),
+			newToken(tokenTypeAt, nil),
+			newToken(tokenTypeID, "compare"),
+		},
 	}}
 
 	for _, test := range tests {
 		l := newLexer(test.program)
 		allTokens := l.getAllTokens()
+		// Positions are tested separately.
+		for _, t := range allTokens {
+			t.pos = position{}
+		}
 		if !reflect.DeepEqual(allTokens, test.tokens) {
 			t.Fatalf("Expected to get %v.\nGot %v", test.tokens, allTokens)
 		}
 	}
 }
+
+func TestLexerPositions(t *testing.T) {
+	program := `PROGRAM p;
+{ comment
+  over two lines } BEGIN
+	a[1] := 2
+END.`
+	expect := []position{
+		{1, 1}, {1, 9}, {1, 10},
+		{3, 20},
+		{4, 2}, {4, 3}, {4, 4}, {4, 5}, {4, 7}, {4, 10},
+		{5, 1}, {5, 4},
+	}
+	tokens := newLexer(program).getAllTokens()
+	if len(tokens) != len(expect) {
+		t.Fatalf("Expected %d tokens. Got %v", len(expect), tokens)
+	}
+	for index, token := range tokens {
+		if token.pos != expect[index] {
+			t.Fatalf("Expected %v at %v. Got %v", token, expect[index], token.pos)
+		}
+	}
+}
@@ -1,96 +1,343 @@
 package go_pascal
 
+import (
+	"math"
+	"math/big"
+	"strings"
+)
+
+// add adds numbers, concatenates strings, or unites sets. Numbers are
+// promoted to complex if the other operand is complex.
 func add(left, right interface{}) interface{} {
-	if leftInt, ok := left.(int); ok {
-		if rightInt, ok := right.(int); ok {
-			return leftInt + rightInt
-		} else {
-			rightFloat := right.(float64)
-			return float64(leftInt) + rightFloat
-		}
-	} else {
-		leftFloat := left.(float64)
-		if rightInt, ok := right.(int); ok {
-			return leftFloat + float64(rightInt)
-		} else {
-			rightFloat := right.(float64)
-			return float64(leftFloat) + rightFloat
-		}
+	if leftSet, ok := left.(set); ok {
+		return leftSet.union(right.(set))
 	}
+	if isTextValue(left) {
+		return textValue(left) + textValue(right)
+	}
+	if isComplexValue(left) || isComplexValue(right) {
+		return toComplex(left) + toComplex(right)
+	}
+	if isIntegerValue(left) && isIntegerValue(right) {
+		return arithmeticBig(tokenTypePlus, left, right)
+	}
+	if decimalOperands(left, right) {
+		return toDecimal(left).add(toDecimal(right))
+	}
+	return toFloat(left) + toFloat(right)
 }
 
+// minus subtracts numbers, or returns the difference of sets.
 func minus(left, right interface{}) interface{} {
-	if leftInt, ok := left.(int); ok {
-		if rightInt, ok := right.(int); ok {
-			return leftInt - rightInt
-		} else {
-			rightFloat := right.(float64)
-			return float64(leftInt) - rightFloat
-		}
-	} else {
-		leftFloat := left.(float64)
-		if rightInt, ok := right.(int); ok {
-			return leftFloat - float64(rightInt)
-		} else {
-			rightFloat := right.(float64)
-			return float64(leftFloat) - rightFloat
-		}
+	if leftSet, ok := left.(set); ok {
+		return leftSet.difference(right.(set))
+	}
+	if isComplexValue(left) || isComplexValue(right) {
+		return toComplex(left) - toComplex(right)
+	}
+	if isIntegerValue(left) && isIntegerValue(right) {
+		return arithmeticBig(tokenTypeMinus, left, right)
 	}
+	if decimalOperands(left, right) {
+		return toDecimal(left).sub(toDecimal(right))
+	}
+	return toFloat(left) - toFloat(right)
 }
 
+// mul multiplies numbers, or intersects sets.
 func mul(left, right interface{}) interface{} {
+	if leftSet, ok := left.(set); ok {
+		return leftSet.intersection(right.(set))
+	}
+	if isComplexValue(left) || isComplexValue(right) {
+		return toComplex(left) * toComplex(right)
+	}
+	if isIntegerValue(left) && isIntegerValue(right) {
+		return arithmeticBig(tokenTypeMul, left, right)
+	}
+	if decimalOperands(left, right) {
+		return toDecimal(left).mul(toDecimal(right))
+	}
+	return toFloat(left) * toFloat(right)
+}
+
+// divReal divides numbers. The result is real unless one of them is
+// complex, or a CURRENCY or DECIMAL value. Fixed-point quotients are rounded
+// to decimalDivisionScale decimals. right must not be zero for integer and
+// fixed-point operands.
+func divReal(left, right interface{}) interface{} {
+	if isComplexValue(left) || isComplexValue(right) {
+		return toComplex(left) / toComplex(right)
+	}
+	if decimalOperands(left, right) {
+		return toDecimal(left).quo(toDecimal(right), decimalDivisionScale)
+	}
+	return toFloat(left) / toFloat(right)
+}
+
+// divInt divides integers and truncates the result towards zero. right
+// must not be zero.
+func divInt(left, right interface{}) interface{} {
+	return arithmeticBig(tokenTypeDivInteger, left, right)
+}
+
+// modInt returns the remainder of an integer division with the semantics of
+// ISO Pascal: the result is never negative, e.g. -7 MOD 3 is 2. right must be
+// positive.
+func modInt(left, right interface{}) interface{} {
 	if leftInt, ok := left.(int); ok {
 		if rightInt, ok := right.(int); ok {
-			return leftInt * rightInt
-		} else {
-			rightFloat := right.(float64)
-			return float64(leftInt) * rightFloat
-		}
-	} else {
-		leftFloat := left.(float64)
-		if rightInt, ok := right.(int); ok {
-			return leftFloat * float64(rightInt)
-		} else {
-			rightFloat := right.(float64)
-			return float64(leftFloat) * rightFloat
+			m := leftInt % rightInt
+			if m < 0 {
+				m += rightInt
+			}
+			return m
 		}
 	}
+	return normalizeInt(new(big.Int).Mod(bigInt(left), bigInt(right)))
 }
 
-func divReal(left, right interface{}) interface{} {
+// bitwise applies AND, OR or XOR to the bits of two integers. Negative big
+// integers behave as if they had infinitely many leading one bits.
+func bitwise(t tokenType, left, right interface{}) interface{} {
 	if leftInt, ok := left.(int); ok {
 		if rightInt, ok := right.(int); ok {
-			return leftInt / rightInt
-		} else {
-			rightFloat := right.(float64)
-			return float64(leftInt) / rightFloat
+			switch t {
+			case tokenTypeAnd:
+				return leftInt & rightInt
+			case tokenTypeOr:
+				return leftInt | rightInt
+			default:
+				return leftInt ^ rightInt
+			}
 		}
-	} else {
-		leftFloat := left.(float64)
+	}
+	result := new(big.Int)
+	switch t {
+	case tokenTypeAnd:
+		result.And(bigInt(left), bigInt(right))
+	case tokenTypeOr:
+		result.Or(bigInt(left), bigInt(right))
+	default:
+		result.Xor(bigInt(left), bigInt(right))
+	}
+	return normalizeInt(result)
+}
+
+// shift shifts the bits of an integer of type typ. SHR is a logical shift,
+// which fills the vacated bits with zeros, and counts are taken modulo 64 as
+// on x86-64.
+func shift(t tokenType, value, count int, typ *integerType) int {
+	count &= 63
+	if t == tokenTypeShl {
+		return typ.wrap(value << uint(count))
+	}
+	bits := uint64(value)
+	if typ.bits < 64 {
+		bits &= 1<<typ.bits - 1
+	}
+	return typ.wrap(int(bits >> uint(count)))
+}
+
+// shiftBig shifts the bits of an integer of a type without bounds. SHL
+// never overflows, and SHR is an arithmetic shift, which keeps the sign.
+// count must not be negative.
+func shiftBig(t tokenType, value interface{}, count int) interface{} {
+	if t == tokenTypeShl {
+		return normalizeInt(new(big.Int).Lsh(bigInt(value), uint(count)))
+	}
+	return normalizeInt(new(big.Int).Rsh(bigInt(value), uint(count)))
+}
+
+// arithmeticBig applies +, -, * or DIV to integers. Results that do not fit
+// into an int are big integers, so they never overflow.
+func arithmeticBig(t tokenType, left, right interface{}) interface{} {
+	if leftInt, ok := left.(int); ok {
 		if rightInt, ok := right.(int); ok {
-			return leftFloat / float64(rightInt)
-		} else {
-			rightFloat := right.(float64)
-			return float64(leftFloat) / rightFloat
+			if result, overflow := arithmeticInt(t, leftInt, rightInt); !overflow {
+				return result
+			}
 		}
 	}
+	result := new(big.Int)
+	switch t {
+	case tokenTypePlus:
+		result.Add(bigInt(left), bigInt(right))
+	case tokenTypeMinus:
+		result.Sub(bigInt(left), bigInt(right))
+	case tokenTypeMul:
+		result.Mul(bigInt(left), bigInt(right))
+	default:
+		result.Quo(bigInt(left), bigInt(right))
+	}
+	return normalizeInt(result)
 }
 
-func divInt(left, right interface{}) interface{} {
+// arithmeticInt applies +, -, * or DIV to integers. overflow is true if the
+// result does not fit into 64 bits, in which case it has wrapped around.
+func arithmeticInt(t tokenType, left, right int) (result int, overflow bool) {
+	switch t {
+	case tokenTypePlus:
+		result = left + right
+		return result, result > left != (right > 0)
+	case tokenTypeMinus:
+		result = left - right
+		return result, result < left != (right > 0)
+	case tokenTypeMul:
+		result = left * right
+		return result, left != 0 && (result/left != right || left == -1 && right == math.MinInt64)
+	default:
+		// Go defines MinInt64 / -1 as MinInt64 instead of panicking.
+		return left / right, left == math.MinInt64 && right == -1
+	}
+}
+
+// compare returns a negative number, zero or a positive number if left is
+// less than, equal to or greater than right. Booleans are ordered with false
+// before true. Pointers, routines, dynamic arrays and complex numbers are
+// only compared for equality.
+func compare(left, right interface{}) int {
+	if isComplexValue(left) || isComplexValue(right) {
+		if toComplex(left) == toComplex(right) {
+			return 0
+		}
+		return 1
+	}
+	// NIL is a nil *heapCell, which is not a *routineValue.
+	leftRoutine, leftIsRoutine := left.(*routineValue)
+	rightRoutine, rightIsRoutine := right.(*routineValue)
+	if leftIsRoutine || rightIsRoutine {
+		if leftRoutine.equal(rightRoutine) {
+			return 0
+		}
+		return 1
+	}
+	// Empty dynamic arrays are nil, so they equal NIL.
+	leftArray, leftIsArray := left.(*dynamicArray)
+	rightArray, rightIsArray := right.(*dynamicArray)
+	if leftIsArray || rightIsArray {
+		if leftArray == rightArray {
+			return 0
+		}
+		return 1
+	}
+	if leftPointer, ok := left.(*heapCell); ok {
+		if leftPointer == right.(*heapCell) {
+			return 0
+		}
+		return 1
+	}
+	if isTextValue(left) {
+		return strings.Compare(textValue(left), textValue(right))
+	}
+	if leftBool, ok := left.(bool); ok {
+		rightBool := right.(bool)
+		switch {
+		case leftBool == rightBool:
+			return 0
+		case rightBool:
+			return -1
+		default:
+			return 1
+		}
+	}
 	if leftInt, ok := left.(int); ok {
 		if rightInt, ok := right.(int); ok {
-			return int(leftInt / rightInt)
-		} else {
-			rightFloat := right.(float64)
-			return int(float64(leftInt) / rightFloat)
+			switch {
+			case leftInt < rightInt:
+				return -1
+			case leftInt > rightInt:
+				return 1
+			}
+			return 0
 		}
-	} else {
-		leftFloat := left.(float64)
-		if rightInt, ok := right.(int); ok {
-			return int(leftFloat / float64(rightInt))
-		} else {
-			rightFloat := right.(float64)
-			return int(float64(leftFloat) / rightFloat)
+	}
+	if isIntegerValue(left) && isIntegerValue(right) {
+		return bigInt(left).Cmp(bigInt(right))
+	}
+	if decimalOperands(left, right) {
+		return toDecimal(left).cmp(toDecimal(right))
+	}
+	leftFloat, rightFloat := toFloat(left), toFloat(right)
+	switch {
+	case leftFloat < rightFloat:
+		return -1
+	case leftFloat > rightFloat:
+		return 1
+	}
+	return 0
+}
+
+func isTextValue(v interface{}) bool {
+	switch v.(type) {
+	case string, byte:
+		return true
+	}
+	return false
+}
+
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f
+	case currency, decimal:
+		return toDecimal(v).float()
+	}
+	return v.(float64)
+}
+
+// isIntegerValue returns true for the values of integer types.
+func isIntegerValue(v interface{}) bool {
+	switch v.(type) {
+	case int, *big.Int:
+		return true
+	}
+	return false
+}
+
+// bigInt returns an integer as a big integer.
+func bigInt(v interface{}) *big.Int {
+	if i, ok := v.(int); ok {
+		return big.NewInt(int64(i))
+	}
+	return v.(*big.Int)
+}
+
+// normalizeInt returns a big integer as an int if it fits into one. Values
+// of integer types are only big integers if they have to be.
+func normalizeInt(b *big.Int) interface{} {
+	if b.IsInt64() {
+		return int(b.Int64())
+	}
+	return b
+}
+
+// maxUint64 masks the lowest 64 bits of a big integer.
+var maxUint64 = new(big.Int).SetUint64(math.MaxUint64)
+
+// lowBits returns the lowest 64 bits of a big integer in two's complement,
+// which is what it wraps around to in 64 bits.
+func lowBits(b *big.Int) int {
+	return int(new(big.Int).And(b, maxUint64).Uint64())
+}
+
+// sign returns -1, 0 or 1 for a negative, zero or positive integer or
+// fixed-point number.
+func sign(v interface{}) int {
+	switch v := v.(type) {
+	case int:
+		switch {
+		case v < 0:
+			return -1
+		case v > 0:
+			return 1
 		}
+		return 0
+	case *big.Int:
+		return v.Sign()
 	}
+	return toDecimal(v).sign()
 }
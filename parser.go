@@ -17,12 +17,13 @@ type parser struct {
 	token *token
 }
 
+// newParser creates a parser. No input is read before parsing starts, so the
+// lexer options can be changed before the compiler directives in the input
+// are applied.
 func newParser(input string) *parser {
-	p := &parser{
+	return &parser{
 		lexer: newLexer(input),
 	}
-	p.token = p.lexer.getNextToken()
-	return p
 }
 
 func (p *parser) newErrUnexpectedToken(expectedTokenType tokenType) error {
@@ -33,6 +34,26 @@ func (p *parser) newErrUnexpectedToken(expectedTokenType tokenType) error {
 	}
 }
 
+func isAddingOperator(t tokenType) bool {
+	return t == tokenTypePlus || t == tokenTypeMinus || t == tokenTypeOr || t == tokenTypeXor
+}
+
+func isMultiplyingOperator(t tokenType) bool {
+	switch t {
+	case tokenTypeMul, tokenTypeDivReal, tokenTypeDivInteger, tokenTypeMod, tokenTypeAnd, tokenTypeShl, tokenTypeShr:
+		return true
+	}
+	return false
+}
+
+func isRelationalOperator(t tokenType) bool {
+	switch t {
+	case tokenTypeEqual, tokenTypeNotEqual, tokenTypeLess, tokenTypeLessEqual, tokenTypeGreater, tokenTypeGreaterEqual, tokenTypeIn:
+		return true
+	}
+	return false
+}
+
 func (p *parser) eat(expectedTokenType tokenType) error {
 	if p.token.tokenType != expectedTokenType {
 		return p.newErrUnexpectedToken(expectedTokenType)
@@ -41,6 +62,8 @@ func (p *parser) eat(expectedTokenType tokenType) error {
 	return nil
 }
 
+// factor parses a factor. A sign is accepted here as well, so that a
+// factor such as -b can follow an operator as in a * -b.
 func (p *parser) factor() (node, error) {
 	var n node
 	var err error
@@ -52,20 +75,48 @@ func (p *parser) factor() (node, error) {
 		if err != nil {
 			return nil, err
 		}
-		n = newUnaryNode(t, child)
+		n = newUnaryNode(t, child, p.lexer.options.switches)
 	case tokenTypeMinus:
 		p.eat(tokenTypeMinus)
 		child, err := p.factor()
 		if err != nil {
 			return nil, err
 		}
-		n = newUnaryNode(t, child)
+		n = newUnaryNode(t, child, p.lexer.options.switches)
+	case tokenTypeNot:
+		p.eat(tokenTypeNot)
+		child, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		n = newUnaryNode(t, child, p.lexer.options.switches)
+	case tokenTypeBooleanConst:
+		p.eat(tokenTypeBooleanConst)
+		n = newValueNode(t)
 	case tokenTypeIntegerConst:
 		p.eat(tokenTypeIntegerConst)
 		n = newValueNode(t)
 	case tokenTypeRealConst:
 		p.eat(tokenTypeRealConst)
 		n = newValueNode(t)
+	case tokenTypeNil:
+		p.eat(tokenTypeNil)
+		n = newValueNode(t)
+	case tokenTypeStringConst:
+		p.eat(tokenTypeStringConst)
+		n = newValueNode(t)
+	case tokenTypeAt:
+		p.eat(tokenTypeAt)
+		name := p.token
+		if err = p.eat(tokenTypeID); err != nil {
+			return nil, err
+		}
+		n = newAddressNode(t, newVarNode(name))
+	case tokenTypeLBracket:
+		n, err = p.setConstructor()
+		if err != nil {
+			return nil, err
+		}
 	case tokenTypeLParen:
 		p.eat(tokenTypeLParen)
 		n, err = p.expr()
@@ -80,68 +131,255 @@ func (p *parser) factor() (node, error) {
 		if err != nil {
 			return nil, err
 		}
+		if p.token.tokenType == tokenTypeLParen {
+			switches := p.lexer.options.switches
+			args, err := p.actualParameters()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := n.(*varNode); ok {
+				n = newFunctionCallNode(t, args, switches)
+			} else {
+				call := newFunctionCallNode(designatorName(t, n), args, switches)
+				call.(*functionCallNode).variable = n
+				n = call
+			}
+		}
 	}
 	return n, nil
 }
 
+// setConstructor parses a set such as [1, 3..5].
+func (p *parser) setConstructor() (node, error) {
+	t := p.token
+	if err := p.eat(tokenTypeLBracket); err != nil {
+		return nil, err
+	}
+	var elements []node
+	for p.token.tokenType != tokenTypeRBracket {
+		element, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.token.tokenType == tokenTypeRange {
+			rangeToken := p.token
+			p.eat(tokenTypeRange)
+			high, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			element = newRangeNode(rangeToken, element, high)
+		}
+		elements = append(elements, element)
+		if p.token.tokenType != tokenTypeComma {
+			break
+		}
+		p.eat(tokenTypeComma)
+	}
+	if err := p.eat(tokenTypeRBracket); err != nil {
+		return nil, err
+	}
+	return newSetNode(t, elements), nil
+}
+
 func (p *parser) term() (node, error) {
 	n, err := p.factor()
 	if err != nil {
 		return nil, err
 	}
-	for p.token.tokenType == tokenTypeMul || p.token.tokenType == tokenTypeDivReal || p.token.tokenType == tokenTypeDivInteger {
+	for isMultiplyingOperator(p.token.tokenType) {
 		t := p.token
-		if p.token.tokenType == tokenTypeMul {
-			p.eat(tokenTypeMul)
-		} else if p.token.tokenType == tokenTypeDivReal {
-			p.eat(tokenTypeDivReal)
-		} else {
-			p.eat(tokenTypeDivInteger)
-		}
+		switches := p.lexer.options.switches
+		p.eat(t.tokenType)
 		right, err := p.factor()
 		if err != nil {
 			return nil, err
 		}
-		n = newBinaryNode(t, n, right)
+		n = newBinaryNode(t, n, right, switches)
+	}
+	return n, nil
+}
+
+// simpleExpression parses terms joined by adding operators. A leading sign
+// applies to the whole first term, as in ISO Pascal, so -7 MOD 3 is
+// -(7 MOD 3).
+func (p *parser) simpleExpression() (node, error) {
+	var n node
+	var err error
+	if t := p.token; t.tokenType == tokenTypePlus || t.tokenType == tokenTypeMinus {
+		switches := p.lexer.options.switches
+		p.eat(t.tokenType)
+		child, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		n = newUnaryNode(t, child, switches)
+	} else if n, err = p.term(); err != nil {
+		return nil, err
+	}
+	for isAddingOperator(p.token.tokenType) {
+		t := p.token
+		switches := p.lexer.options.switches
+		p.eat(t.tokenType)
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		n = newBinaryNode(t, n, right, switches)
 	}
 	return n, nil
 }
 
+// expr parses an expression. Relational operators have the lowest precedence
+// and do not associate, so a = b = c is rejected as in Pascal.
 func (p *parser) expr() (node, error) {
-	n, err := p.term()
+	n, err := p.simpleExpression()
 	if err != nil {
 		return nil, err
 	}
-	for p.token.tokenType == tokenTypePlus || p.token.tokenType == tokenTypeMinus {
+	if isRelationalOperator(p.token.tokenType) {
 		t := p.token
-		if p.token.tokenType == tokenTypePlus {
-			p.eat(tokenTypePlus)
-		} else {
-			p.eat(tokenTypeMinus)
-		}
-		right, err := p.term()
+		switches := p.lexer.options.switches
+		p.eat(t.tokenType)
+		right, err := p.simpleExpression()
 		if err != nil {
 			return nil, err
 		}
-		n = newBinaryNode(t, n, right)
+		n = newBinaryNode(t, n, right, switches)
 	}
 	return n, nil
 }
 
+// variable parses an identifier followed by any number of indexes, field
+// selectors and pointer dereferences.
 func (p *parser) variable() (node, error) {
 	t := p.token
 	if err := p.eat(tokenTypeID); err != nil {
 		return nil, err
 	}
-	return newVarNode(t), nil
+	n := newVarNode(t)
+	for p.token.tokenType == tokenTypeLBracket || p.token.tokenType == tokenTypeDot || p.token.tokenType == tokenTypeCaret {
+		if p.token.tokenType == tokenTypeCaret {
+			n = newDerefNode(p.token, n)
+			p.eat(tokenTypeCaret)
+			continue
+		}
+		if p.token.tokenType == tokenTypeDot {
+			p.eat(tokenTypeDot)
+			field := p.token
+			if err := p.eat(tokenTypeID); err != nil {
+				return nil, err
+			}
+			n = newFieldNode(field, n)
+			continue
+		}
+		p.eat(tokenTypeLBracket)
+		for {
+			t := p.token
+			index, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			n = newIndexNode(t, n, index)
+			if p.token.tokenType != tokenTypeComma {
+				break
+			}
+			p.eat(tokenTypeComma)
+		}
+		if err := p.eat(tokenTypeRBracket); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// designatorName returns the token that names a call through the variable
+// designator n, such as the field of r.handler, for messages. first is the
+// first token of n.
+func designatorName(first *token, n node) *token {
+	if t := nodeToken(n); t != nil && t.tokenType == tokenTypeID {
+		return t
+	}
+	return first
+}
+
+// actualParameters parses the optional argument list of a routine call. An
+// argument may be followed by a field width and a number of decimals, which
+// the semantic analyzer only accepts for Write and WriteLn.
+func (p *parser) actualParameters() ([]node, error) {
+	if p.token.tokenType != tokenTypeLParen {
+		return nil, nil
+	}
+	p.eat(tokenTypeLParen)
+	var args []node
+	for p.token.tokenType != tokenTypeRParen {
+		if len(args) > 0 {
+			if err := p.eat(tokenTypeComma); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.token.tokenType == tokenTypeColon {
+			if arg, err = p.format(arg); err != nil {
+				return nil, err
+			}
+		}
+		args = append(args, arg)
+	}
+	p.eat(tokenTypeRParen)
+	return args, nil
+}
+
+// format parses the field width and the optional number of decimals of
+// value.
+func (p *parser) format(value node) (node, error) {
+	t := p.token
+	p.eat(tokenTypeColon)
+	width, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	var decimals node
+	if p.token.tokenType == tokenTypeColon {
+		p.eat(tokenTypeColon)
+		if decimals, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+	return newFormatNode(t, value, width, decimals), nil
 }
 
-func (p *parser) assignmentStatement() (node, error) {
+// assignmentOrCallStatement parses a statement starting with an identifier,
+// which is either an assignment or a procedure call.
+func (p *parser) assignmentOrCallStatement() (node, error) {
 	t := p.token
+	switches := p.lexer.options.switches
 	left, err := p.variable()
 	if err != nil {
 		return nil, err
 	}
+	if _, ok := left.(*varNode); ok && p.token.tokenType != tokenTypeAssign {
+		args, err := p.actualParameters()
+		if err != nil {
+			return nil, err
+		}
+		return newProcedureCallNode(t, args, switches), nil
+	}
+	// A call through a procedural variable that is a component, such as
+	// r.handler(1) or ops[i](x), needs parentheses, as the component alone
+	// starts an assignment.
+	if p.token.tokenType == tokenTypeLParen {
+		args, err := p.actualParameters()
+		if err != nil {
+			return nil, err
+		}
+		call := newProcedureCallNode(designatorName(t, left), args, switches)
+		call.(*procedureCallNode).variable = left
+		return call, nil
+	}
 	if err = p.eat(tokenTypeAssign); err != nil {
 		return nil, err
 	}
@@ -149,7 +387,251 @@ func (p *parser) assignmentStatement() (node, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newAssignNode(t, left, right), nil
+	return newAssignNode(t, left, right, switches), nil
+}
+
+// ifStatement parses an if statement. An ELSE always belongs to the nearest
+// IF, which falls out of parsing the THEN branch greedily.
+func (p *parser) ifStatement() (node, error) {
+	if err := p.eat(tokenTypeIf); err != nil {
+		return nil, err
+	}
+	condition, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.eat(tokenTypeThen); err != nil {
+		return nil, err
+	}
+	thenNode, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	var elseNode node
+	if p.token.tokenType == tokenTypeElse {
+		p.eat(tokenTypeElse)
+		elseNode, err = p.statement()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newIfNode(condition, thenNode, elseNode), nil
+}
+
+func (p *parser) withStatement() (node, error) {
+	if err := p.eat(tokenTypeWith); err != nil {
+		return nil, err
+	}
+	var records []node
+	for {
+		record, err := p.variable()
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+		if p.token.tokenType != tokenTypeComma {
+			break
+		}
+		p.eat(tokenTypeComma)
+	}
+	if err := p.eat(tokenTypeDo); err != nil {
+		return nil, err
+	}
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	return newWithNode(records, body), nil
+}
+
+func (p *parser) whileStatement() (node, error) {
+	if err := p.eat(tokenTypeWhile); err != nil {
+		return nil, err
+	}
+	condition, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.eat(tokenTypeDo); err != nil {
+		return nil, err
+	}
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	return newWhileNode(condition, body), nil
+}
+
+func (p *parser) repeatStatement() (node, error) {
+	if err := p.eat(tokenTypeRepeat); err != nil {
+		return nil, err
+	}
+	statements, err := p.statementList()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.eat(tokenTypeUntil); err != nil {
+		return nil, err
+	}
+	condition, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return newRepeatNode(statements, condition), nil
+}
+
+func (p *parser) forStatement() (node, error) {
+	switches := p.lexer.options.switches
+	if err := p.eat(tokenTypeFor); err != nil {
+		return nil, err
+	}
+	variable, err := p.variable()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.eat(tokenTypeAssign); err != nil {
+		return nil, err
+	}
+	start, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	downto := p.token.tokenType == tokenTypeDownto
+	if downto {
+		p.eat(tokenTypeDownto)
+	} else if err = p.eat(tokenTypeTo); err != nil {
+		return nil, err
+	}
+	end, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.eat(tokenTypeDo); err != nil {
+		return nil, err
+	}
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	return newForNode(variable, start, end, downto, body, switches), nil
+}
+
+// constant parses a literal or a named constant such as a case label.
+func (p *parser) constant() (node, error) {
+	t := p.token
+	switch t.tokenType {
+	case tokenTypePlus, tokenTypeMinus:
+		p.eat(t.tokenType)
+		child, err := p.constant()
+		if err != nil {
+			return nil, err
+		}
+		return newUnaryNode(t, child, p.lexer.options.switches), nil
+	case tokenTypeBooleanConst, tokenTypeIntegerConst, tokenTypeRealConst, tokenTypeStringConst:
+		p.eat(t.tokenType)
+		return newValueNode(t), nil
+	case tokenTypeID:
+		p.eat(tokenTypeID)
+		return newVarNode(t), nil
+	default:
+		return nil, p.newErrUnexpectedToken(tokenTypeIntegerConst)
+	}
+}
+
+// caseLabel parses a constant or a range of constants.
+func (p *parser) caseLabel() (node, error) {
+	low, err := p.constant()
+	if err != nil {
+		return nil, err
+	}
+	if p.token.tokenType != tokenTypeRange {
+		return low, nil
+	}
+	t := p.token
+	p.eat(tokenTypeRange)
+	high, err := p.constant()
+	if err != nil {
+		return nil, err
+	}
+	return newRangeNode(t, low, high), nil
+}
+
+// caseLabels parses the labels of a case branch or of a record variant,
+// including the colon after them.
+func (p *parser) caseLabels() ([]node, error) {
+	var labels []node
+	for {
+		label, err := p.caseLabel()
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, label)
+		if p.token.tokenType != tokenTypeComma {
+			break
+		}
+		p.eat(tokenTypeComma)
+	}
+	if err := p.eat(tokenTypeColon); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+func (p *parser) caseBranch() (node, error) {
+	labels, err := p.caseLabels()
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	return newCaseBranchNode(labels, body), nil
+}
+
+// caseStatement parses a case statement. As in Turbo Pascal, the ELSE branch
+// may hold several statements.
+func (p *parser) caseStatement() (node, error) {
+	t := p.token
+	if err := p.eat(tokenTypeCase); err != nil {
+		return nil, err
+	}
+	selector, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.eat(tokenTypeOf); err != nil {
+		return nil, err
+	}
+	branch, err := p.caseBranch()
+	if err != nil {
+		return nil, err
+	}
+	branches := []node{branch}
+	for p.token.tokenType == tokenTypeSemi {
+		p.eat(tokenTypeSemi)
+		if p.token.tokenType == tokenTypeElse || p.token.tokenType == tokenTypeEnd {
+			break
+		}
+		branch, err = p.caseBranch()
+		if err != nil {
+			return nil, err
+		}
+		branches = append(branches, branch)
+	}
+	var elseNode node
+	if p.token.tokenType == tokenTypeElse {
+		p.eat(tokenTypeElse)
+		statements, err := p.statementList()
+		if err != nil {
+			return nil, err
+		}
+		elseNode = newCompoundNode(statements)
+	}
+	if err = p.eat(tokenTypeEnd); err != nil {
+		return nil, err
+	}
+	return newCaseNode(t, selector, branches, elseNode), nil
 }
 
 func (p *parser) statement() (node, error) {
@@ -162,7 +644,37 @@ func (p *parser) statement() (node, error) {
 			return nil, err
 		}
 	case tokenTypeID:
-		n, err = p.assignmentStatement()
+		n, err = p.assignmentOrCallStatement()
+		if err != nil {
+			return nil, err
+		}
+	case tokenTypeIf:
+		n, err = p.ifStatement()
+		if err != nil {
+			return nil, err
+		}
+	case tokenTypeWhile:
+		n, err = p.whileStatement()
+		if err != nil {
+			return nil, err
+		}
+	case tokenTypeRepeat:
+		n, err = p.repeatStatement()
+		if err != nil {
+			return nil, err
+		}
+	case tokenTypeFor:
+		n, err = p.forStatement()
+		if err != nil {
+			return nil, err
+		}
+	case tokenTypeCase:
+		n, err = p.caseStatement()
+		if err != nil {
+			return nil, err
+		}
+	case tokenTypeWith:
+		n, err = p.withStatement()
 		if err != nil {
 			return nil, err
 		}
@@ -204,17 +716,306 @@ func (p *parser) compoundStatement() (node, error) {
 	return newCompoundNode(statements), nil
 }
 
+// typeSpec parses a type keyword, a type identifier, a string type, an
+// enumeration, an array, a record, a set, a file, a pointer, a procedural
+// type or a subrange.
+// The bounds of a subrange may be constant expressions, so an identifier is a
+// type name only if no RANGE follows.
 func (p *parser) typeSpec() (node, error) {
 	t := p.token
 	switch t.tokenType {
-	case tokenTypeInteger:
-		p.eat(tokenTypeInteger)
-		return newTypeNode(t), nil
-	case tokenTypeReal:
-		p.eat(tokenTypeReal)
+	case tokenTypeInteger, tokenTypeReal, tokenTypeBoolean, tokenTypeChar:
+		p.eat(t.tokenType)
 		return newTypeNode(t), nil
-	default:
-		return nil, p.newErrUnexpectedToken(tokenTypeReal)
+	case tokenTypeString:
+		p.eat(tokenTypeString)
+		if p.token.tokenType != tokenTypeLBracket {
+			return newTypeNode(t), nil
+		}
+		p.eat(tokenTypeLBracket)
+		size, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.eat(tokenTypeRBracket); err != nil {
+			return nil, err
+		}
+		return newStringTypeNode(t, size), nil
+	case tokenTypeLParen:
+		return p.enumType()
+	case tokenTypeArray:
+		return p.arrayType()
+	case tokenTypeRecord:
+		return p.recordType()
+	case tokenTypeSet:
+		p.eat(tokenTypeSet)
+		if err := p.eat(tokenTypeOf); err != nil {
+			return nil, err
+		}
+		base, err := p.typeSpec()
+		if err != nil {
+			return nil, err
+		}
+		return newSetTypeNode(t, base), nil
+	case tokenTypeFile:
+		p.eat(tokenTypeFile)
+		if err := p.eat(tokenTypeOf); err != nil {
+			return nil, err
+		}
+		element, err := p.typeSpec()
+		if err != nil {
+			return nil, err
+		}
+		return newFileTypeNode(t, element), nil
+	case tokenTypeCaret:
+		p.eat(tokenTypeCaret)
+		target := p.token
+		switch target.tokenType {
+		case tokenTypeInteger, tokenTypeReal, tokenTypeBoolean, tokenTypeChar, tokenTypeString, tokenTypeID:
+			p.eat(target.tokenType)
+		default:
+			return nil, p.newErrUnexpectedToken(tokenTypeID)
+		}
+		return newPointerTypeNode(t, newTypeNode(target)), nil
+	case tokenTypeProcedure, tokenTypeFunction:
+		p.eat(t.tokenType)
+		return p.procedureType(t)
+	}
+	low, err := p.simpleExpression()
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := low.(*varNode); ok && p.token.tokenType != tokenTypeRange {
+		return newTypeNode(v.t), nil
+	}
+	rangeToken := p.token
+	if err = p.eat(tokenTypeRange); err != nil {
+		return nil, err
+	}
+	high, err := p.simpleExpression()
+	if err != nil {
+		return nil, err
+	}
+	return newSubrangeTypeNode(rangeToken, low, high), nil
+}
+
+// procedureType parses the parameters and the result type of a procedural
+// type, which follow the keyword t.
+func (p *parser) procedureType(t *token) (node, error) {
+	params, err := p.formalParameterList()
+	if err != nil {
+		return nil, err
+	}
+	var returnType node
+	if t.tokenType == tokenTypeFunction {
+		if err = p.eat(tokenTypeColon); err != nil {
+			return nil, err
+		}
+		if returnType, err = p.typeSpec(); err != nil {
+			return nil, err
+		}
+	}
+	return newProcedureTypeNode(t, params, returnType), nil
+}
+
+// arrayType parses an array type. The index types of a multi-dimensional
+// array become nested array types. An array without index types is a dynamic
+// array, or an open array in a parameter list.
+func (p *parser) arrayType() (node, error) {
+	t := p.token
+	if err := p.eat(tokenTypeArray); err != nil {
+		return nil, err
+	}
+	if p.token.tokenType == tokenTypeOf {
+		p.eat(tokenTypeOf)
+		element, err := p.typeSpec()
+		if err != nil {
+			return nil, err
+		}
+		return newArrayTypeNode(t, nil, element), nil
+	}
+	if err := p.eat(tokenTypeLBracket); err != nil {
+		return nil, err
+	}
+	var indexTypes []node
+	for {
+		indexType, err := p.typeSpec()
+		if err != nil {
+			return nil, err
+		}
+		indexTypes = append(indexTypes, indexType)
+		if p.token.tokenType != tokenTypeComma {
+			break
+		}
+		p.eat(tokenTypeComma)
+	}
+	if err := p.eat(tokenTypeRBracket); err != nil {
+		return nil, err
+	}
+	if err := p.eat(tokenTypeOf); err != nil {
+		return nil, err
+	}
+	n, err := p.typeSpec()
+	if err != nil {
+		return nil, err
+	}
+	for index := len(indexTypes) - 1; index >= 0; index-- {
+		n = newArrayTypeNode(t, indexTypes[index], n)
+	}
+	return n, nil
+}
+
+func (p *parser) recordType() (node, error) {
+	t := p.token
+	if err := p.eat(tokenTypeRecord); err != nil {
+		return nil, err
+	}
+	fields, err := p.fieldList()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.eat(tokenTypeEnd); err != nil {
+		return nil, err
+	}
+	return newRecordTypeNode(t, fields), nil
+}
+
+// fieldList parses the fixed part of a record, followed by an optional
+// variant part.
+func (p *parser) fieldList() (node, error) {
+	var fields []node
+	for p.token.tokenType == tokenTypeID {
+		names := []*token{p.token}
+		p.eat(tokenTypeID)
+		for p.token.tokenType == tokenTypeComma {
+			p.eat(tokenTypeComma)
+			names = append(names, p.token)
+			if err := p.eat(tokenTypeID); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.eat(tokenTypeColon); err != nil {
+			return nil, err
+		}
+		typeNode, err := p.typeSpec()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, newFieldDeclNode(names, typeNode))
+		if p.token.tokenType != tokenTypeSemi {
+			break
+		}
+		p.eat(tokenTypeSemi)
+	}
+	var variant node
+	if p.token.tokenType == tokenTypeCase {
+		var err error
+		if variant, err = p.variantPart(); err != nil {
+			return nil, err
+		}
+	}
+	return newFieldListNode(fields, variant), nil
+}
+
+// variantPart parses the variant part of a record. The tag field is
+// optional: CASE BOOLEAN OF has no tag, CASE b : BOOLEAN OF has one.
+func (p *parser) variantPart() (node, error) {
+	t := p.token
+	if err := p.eat(tokenTypeCase); err != nil {
+		return nil, err
+	}
+	var tag *token
+	var tagType node
+	if p.token.tokenType == tokenTypeID {
+		id := p.token
+		p.eat(tokenTypeID)
+		if p.token.tokenType == tokenTypeColon {
+			tag = id
+			p.eat(tokenTypeColon)
+		} else {
+			tagType = newTypeNode(id)
+		}
+	}
+	if tagType == nil {
+		var err error
+		if tagType, err = p.typeSpec(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.eat(tokenTypeOf); err != nil {
+		return nil, err
+	}
+	var variants []node
+	for p.token.tokenType != tokenTypeEnd && p.token.tokenType != tokenTypeRParen {
+		labels, err := p.caseLabels()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.eat(tokenTypeLParen); err != nil {
+			return nil, err
+		}
+		fields, err := p.fieldList()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.eat(tokenTypeRParen); err != nil {
+			return nil, err
+		}
+		variants = append(variants, newVariantNode(labels, fields))
+		if p.token.tokenType != tokenTypeSemi {
+			break
+		}
+		p.eat(tokenTypeSemi)
+	}
+	return newVariantPartNode(t, tag, tagType, variants), nil
+}
+
+func (p *parser) enumType() (node, error) {
+	if err := p.eat(tokenTypeLParen); err != nil {
+		return nil, err
+	}
+	var values []*token
+	for {
+		value := p.token
+		if err := p.eat(tokenTypeID); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if p.token.tokenType != tokenTypeComma {
+			break
+		}
+		p.eat(tokenTypeComma)
+	}
+	if err := p.eat(tokenTypeRParen); err != nil {
+		return nil, err
+	}
+	return newEnumTypeNode(values), nil
+}
+
+func (p *parser) typeDeclarations() ([]node, error) {
+	if err := p.eat(tokenTypeType); err != nil {
+		return nil, err
+	}
+	var children []node
+	for {
+		name := p.token
+		if err := p.eat(tokenTypeID); err != nil {
+			return nil, err
+		}
+		if err := p.eat(tokenTypeEqual); err != nil {
+			return nil, err
+		}
+		typeNode, err := p.typeSpec()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.eat(tokenTypeSemi); err != nil {
+			return nil, err
+		}
+		children = append(children, newTypeDeclNode(name, typeNode))
+		if p.token.tokenType != tokenTypeID {
+			return children, nil
+		}
 	}
 }
 
@@ -242,18 +1043,63 @@ func (p *parser) variableDeclaration() ([]node, error) {
 		return nil, err
 	}
 
+	// Only a single variable can be declared with an initial value.
+	var value node
+	if len(idTokens) == 1 && p.token.tokenType == tokenTypeEqual {
+		p.eat(tokenTypeEqual)
+		if value, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+
 	varDeclNodes := make([]node, len(idTokens))
 	for index, idToken := range idTokens {
-		varDeclNodes[index] = newVarDeclNode(newVarNode(idToken), typeNode)
+		varDeclNodes[index] = newVarDeclNode(newVarNode(idToken), typeNode, value)
 	}
 	return varDeclNodes, nil
 }
 
-func (p *parser) declarations() (node, error) {
-	if p.token.tokenType != tokenTypeVar {
-		return newNoOpNode(), nil
+// constantDeclarations parses a CONST section. A constant may be given a
+// type, as in CONST limit : INTEGER = 10.
+func (p *parser) constantDeclarations() ([]node, error) {
+	if err := p.eat(tokenTypeConst); err != nil {
+		return nil, err
+	}
+	var children []node
+	for {
+		name := p.token
+		if err := p.eat(tokenTypeID); err != nil {
+			return nil, err
+		}
+		var typeNode node
+		var err error
+		if p.token.tokenType == tokenTypeColon {
+			p.eat(tokenTypeColon)
+			if typeNode, err = p.typeSpec(); err != nil {
+				return nil, err
+			}
+		}
+		if err = p.eat(tokenTypeEqual); err != nil {
+			return nil, err
+		}
+		value, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.eat(tokenTypeSemi); err != nil {
+			return nil, err
+		}
+		children = append(children, newConstDeclNode(name, typeNode, value))
+		if p.token.tokenType != tokenTypeID {
+			return children, nil
+		}
+	}
+}
+
+func (p *parser) variableDeclarations() ([]node, error) {
+	if err := p.eat(tokenTypeVar); err != nil {
+		return nil, err
 	}
-	p.eat(tokenTypeVar)
 	var children []node
 	for {
 		varDecl, err := p.variableDeclaration()
@@ -268,7 +1114,147 @@ func (p *parser) declarations() (node, error) {
 			break
 		}
 	}
-	return newDeclNode(children), nil
+	return children, nil
+}
+
+// formalParameters parses one group of parameters sharing a type, such as
+// VAR a, b : INTEGER, or a routine parameter as in ISO Pascal, such as
+// FUNCTION f(x: REAL): REAL.
+func (p *parser) formalParameters() ([]node, error) {
+	if t := p.token; t.tokenType == tokenTypeProcedure || t.tokenType == tokenTypeFunction {
+		p.eat(t.tokenType)
+		name := p.token
+		if err := p.eat(tokenTypeID); err != nil {
+			return nil, err
+		}
+		typeNode, err := p.procedureType(t)
+		if err != nil {
+			return nil, err
+		}
+		return []node{newParamNode(newVarNode(name), typeNode, false)}, nil
+	}
+	byRef := p.token.tokenType == tokenTypeVar
+	if byRef {
+		p.eat(tokenTypeVar)
+	}
+	var idTokens []*token
+	for {
+		id := p.token
+		if err := p.eat(tokenTypeID); err != nil {
+			return nil, err
+		}
+		idTokens = append(idTokens, id)
+		if p.token.tokenType != tokenTypeComma {
+			break
+		}
+		p.eat(tokenTypeComma)
+	}
+	if err := p.eat(tokenTypeColon); err != nil {
+		return nil, err
+	}
+	typeNode, err := p.typeSpec()
+	if err != nil {
+		return nil, err
+	}
+	params := make([]node, len(idTokens))
+	for index, idToken := range idTokens {
+		params[index] = newParamNode(newVarNode(idToken), typeNode, byRef)
+	}
+	return params, nil
+}
+
+func (p *parser) formalParameterList() ([]node, error) {
+	if p.token.tokenType != tokenTypeLParen {
+		return nil, nil
+	}
+	p.eat(tokenTypeLParen)
+	var params []node
+	for {
+		group, err := p.formalParameters()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, group...)
+		if p.token.tokenType != tokenTypeSemi {
+			break
+		}
+		p.eat(tokenTypeSemi)
+	}
+	if err := p.eat(tokenTypeRParen); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// routineDeclaration parses a procedure or a function declaration. The
+// heading may be followed by the FORWARD directive instead of a block.
+func (p *parser) routineDeclaration() (node, error) {
+	isFunction := p.token.tokenType == tokenTypeFunction
+	p.eat(p.token.tokenType)
+	name := p.token
+	if err := p.eat(tokenTypeID); err != nil {
+		return nil, err
+	}
+	params, err := p.formalParameterList()
+	if err != nil {
+		return nil, err
+	}
+	var returnType node
+	if isFunction && p.token.tokenType == tokenTypeColon {
+		p.eat(tokenTypeColon)
+		if returnType, err = p.typeSpec(); err != nil {
+			return nil, err
+		}
+	}
+	if err = p.eat(tokenTypeSemi); err != nil {
+		return nil, err
+	}
+	var block node
+	if p.token.tokenType == tokenTypeID && p.token.value == "forward" {
+		p.eat(tokenTypeID)
+	} else if block, err = p.block(); err != nil {
+		return nil, err
+	}
+	if err = p.eat(tokenTypeSemi); err != nil {
+		return nil, err
+	}
+	return newRoutineDeclNode(name, isFunction, params, returnType, block), nil
+}
+
+// declarations parses the declaration sections of a block, which may appear
+// in any order and more than once.
+func (p *parser) declarations() (node, error) {
+	var children []node
+	for {
+		switch p.token.tokenType {
+		case tokenTypeConst:
+			constDecls, err := p.constantDeclarations()
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, constDecls...)
+		case tokenTypeType:
+			typeDecls, err := p.typeDeclarations()
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, typeDecls...)
+		case tokenTypeVar:
+			varDecls, err := p.variableDeclarations()
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, varDecls...)
+		case tokenTypeProcedure, tokenTypeFunction:
+			routineDecl, err := p.routineDeclaration()
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, routineDecl)
+		default:
+			return newDeclNode(children), nil
+		}
+	}
 }
 
 func (p *parser) block() (node, error) {
@@ -284,6 +1270,7 @@ func (p *parser) block() (node, error) {
 }
 
 func (p *parser) program() (node, error) {
+	p.token = p.lexer.getNextToken()
 	if err := p.eat(tokenTypeProgram); err != nil {
 		return nil, err
 	}
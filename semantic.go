@@ -0,0 +1,1649 @@
+package go_pascal
+
+import "fmt"
+
+type errSemantic struct {
+	// token is the offending token, if known.
+	token *token
+	msg   string
+}
+
+func (err *errSemantic) Error() string {
+	if err.token != nil && err.token.pos.line != 0 {
+		return fmt.Sprintf("%v: %v", err.token.pos, err.msg)
+	}
+	return err.msg
+}
+
+func newErrSemantic(t *token, format string, args ...interface{}) error {
+	return &errSemantic{
+		token: t,
+		msg:   fmt.Sprintf(format, args...),
+	}
+}
+
+// semanticAnalyzer resolves identifiers and checks the types of a program
+// before it is interpreted.
+//
+// Assigning to an undeclared identifier declares it implicitly with the type
+// of the assigned expression.
+type semanticAnalyzer struct {
+	options *options
+	scope   *scopedSymbolTable
+	// routines holds the routines whose bodies are being analyzed.
+	routines []*routineSymbol
+	// controlVariables holds the control variables of the enclosing FOR
+	// statements, which must not be assigned to.
+	controlVariables map[*varSymbol]bool
+	// withs holds the records opened by the enclosing WITH statements, the
+	// innermost one last.
+	withs []*withRecord
+	// withCount numbers the hidden variables holding WITH records.
+	withCount int
+	// pointers holds the pointer types whose target types have not been
+	// declared yet.
+	pointers []*forwardPointer
+}
+
+// forwardPointer is a pointer type whose target is declared later in the same
+// TYPE section.
+type forwardPointer struct {
+	typ    *pointerType
+	target *token
+}
+
+// withRecord is a record opened by a WITH statement. symbol is a hidden
+// variable that refers to the record while the body is executed.
+type withRecord struct {
+	typ    *recordType
+	symbol *varSymbol
+}
+
+func newSemanticAnalyzer(options *options) *semanticAnalyzer {
+	return &semanticAnalyzer{
+		options:          options,
+		controlVariables: make(map[*varSymbol]bool),
+	}
+}
+
+func (s *semanticAnalyzer) analyze(root node) error {
+	_, err := s.visit(root)
+	return err
+}
+
+func (s *semanticAnalyzer) visit(n node) (interface{}, error) {
+	return dispatch(s, n)
+}
+
+// visitExpr visits an expression and returns its type.
+func (s *semanticAnalyzer) visitExpr(n node) (dataType, error) {
+	typ, err := s.visit(n)
+	if err != nil {
+		return nil, err
+	}
+	return typ.(dataType), nil
+}
+
+func (s *semanticAnalyzer) visitCondition(n node) error {
+	typ, err := s.visitExpr(n)
+	if err != nil {
+		return err
+	}
+	if !isBoolean(typ) {
+		return newErrSemantic(nodeToken(n), "condition must be %v; got %v", typeBoolean, typ)
+	}
+	return nil
+}
+
+func (s *semanticAnalyzer) visitStatements(statements []node) error {
+	for _, statement := range statements {
+		if _, err := s.visit(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *semanticAnalyzer) VisitProgramNode(n node) (interface{}, error) {
+	r := n.(*programNode)
+	s.scope = newScopedSymbolTable(r.name.value.(string), 1, newBuiltinScope())
+	return s.visit(r.block)
+}
+
+func (s *semanticAnalyzer) VisitBlockNode(n node) (interface{}, error) {
+	r := n.(*blockNode)
+	if _, err := s.visit(r.declNode); err != nil {
+		return nil, err
+	}
+	return s.visit(r.compoundNode)
+}
+
+// VisitDeclNode analyzes the declarations of a block. Every FORWARD
+// declaration must be completed in the same block, and the targets of
+// pointer types must be declared by the end of the TYPE section.
+func (s *semanticAnalyzer) VisitDeclNode(n node) (interface{}, error) {
+	r := n.(*declNode)
+	for _, child := range r.children {
+		if _, ok := child.(*typeDeclNode); !ok {
+			if err := s.resolvePointers(); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := s.visit(child); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.resolvePointers(); err != nil {
+		return nil, err
+	}
+	for _, child := range r.children {
+		if routineDecl, ok := child.(*routineDeclNode); ok && routineDecl.block == nil {
+			id := routineDecl.name.value.(string)
+			if s.scope.lookup(id, true).(*routineSymbol).block == nil {
+				return nil, newErrSemantic(routineDecl.name, "FORWARD declaration of %v is not completed", id)
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (s *semanticAnalyzer) VisitCompoundNode(n node) (interface{}, error) {
+	return nil, s.visitStatements(n.(*compoundNode).children)
+}
+
+// VisitConstDeclNode folds the value of a constant. A typed constant is
+// converted to its type.
+func (s *semanticAnalyzer) VisitConstDeclNode(n node) (interface{}, error) {
+	r := n.(*constDeclNode)
+	id := r.name.value.(string)
+	if s.scope.lookup(id, true) != nil {
+		return nil, newErrSemantic(r.name, "duplicate identifier: %v", id)
+	}
+	var declared dataType
+	if r.typeNode != nil {
+		var err error
+		if declared, err = s.visitExpr(r.typeNode); err != nil {
+			return nil, err
+		}
+		markExact(r.value, declared)
+	}
+	typ, value, err := s.constantExpr(r.value)
+	if err != nil {
+		return nil, err
+	}
+	if declared != nil {
+		if !isAssignable(declared, typ) {
+			return nil, newErrSemantic(r.name, "cannot assign %v to %v constant %v", typ, declared, id)
+		}
+		if err = s.checkSetElements(r.value, declared); err != nil {
+			return nil, err
+		}
+		if err = checkConstantRange(r.name, declared, value); err != nil {
+			return nil, err
+		}
+		typ, value = declared, convertValue(declared, value)
+	}
+	s.scope.insert(newConstSymbol(id, typ, value))
+	return nil, nil
+}
+
+func (s *semanticAnalyzer) VisitVarDeclNode(n node) (interface{}, error) {
+	r := n.(*varDeclNode)
+	typ, err := s.visitExpr(r.typeNode)
+	if err != nil {
+		return nil, err
+	}
+	v := r.varNode.(*varNode)
+	id := v.t.value.(string)
+	if s.scope.lookup(id, true) != nil {
+		return nil, newErrSemantic(v.t, "duplicate identifier: %v", id)
+	}
+	if r.value != nil {
+		markExact(r.value, typ)
+		valueType, value, err := s.constantExpr(r.value)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.checkSetElements(r.value, typ); err != nil {
+			return nil, err
+		}
+		if !isAssignable(typ, valueType) {
+			return nil, newErrSemantic(v.t, "cannot assign %v to %v variable %v", valueType, typ, id)
+		}
+		if err = checkConstantRange(v.t, typ, value); err != nil {
+			return nil, err
+		}
+		r.initialValue = convertValue(typ, value)
+	}
+	v.symbol = newVarSymbol(id, typ, s.scope.level)
+	s.scope.insert(v.symbol)
+	return nil, nil
+}
+
+// formalParameters analyzes the formal parameters of a routine declared at
+// the current level.
+func (s *semanticAnalyzer) formalParameters(params []node) ([]*varSymbol, error) {
+	var symbols []*varSymbol
+	seen := make(map[string]bool)
+	for _, p := range params {
+		param := p.(*paramNode)
+		typ, err := s.visitExpr(param.typeNode)
+		if err != nil {
+			return nil, err
+		}
+		// ARRAY OF T in a parameter list is an open array.
+		if array, ok := param.typeNode.(*arrayTypeNode); ok && array.indexType == nil {
+			typ = newOpenArrayType(typ.(*dynamicArrayType).element)
+		}
+		v := param.varNode.(*varNode)
+		id := v.t.value.(string)
+		if seen[id] {
+			return nil, newErrSemantic(v.t, "duplicate identifier: %v", id)
+		}
+		seen[id] = true
+		v.symbol = newParamSymbol(id, typ, s.scope.level+1, param.byRef)
+		symbols = append(symbols, v.symbol)
+	}
+	return symbols, nil
+}
+
+func sameParameters(a, b []*varSymbol) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for index := range a {
+		if a[index].name != b[index].name || a[index].typ != b[index].typ || a[index].byRef != b[index].byRef {
+			return false
+		}
+	}
+	return true
+}
+
+// routineHeading declares the routine of r, or completes its FORWARD
+// declaration. The heading may be omitted when completing a FORWARD
+// declaration; if it is repeated, it must match.
+func (s *semanticAnalyzer) routineHeading(r *routineDeclNode) (*routineSymbol, error) {
+	id := r.name.value.(string)
+	params, err := s.formalParameters(r.params)
+	if err != nil {
+		return nil, err
+	}
+	var returnType dataType
+	if r.returnType != nil {
+		if returnType, err = s.visitExpr(r.returnType); err != nil {
+			return nil, err
+		}
+	}
+
+	existing := s.scope.lookup(id, true)
+	if forward, ok := existing.(*routineSymbol); ok && forward.block == nil && r.block != nil {
+		if forward.isFunction() != r.isFunction {
+			return nil, newErrSemantic(r.name, "declaration of %v does not match its FORWARD declaration", id)
+		}
+		if len(r.params) > 0 && !sameParameters(forward.params, params) || returnType != nil && returnType != forward.returnType {
+			return nil, newErrSemantic(r.name, "declaration of %v does not match its FORWARD declaration", id)
+		}
+		return forward, nil
+	}
+	if existing != nil {
+		return nil, newErrSemantic(r.name, "duplicate identifier: %v", id)
+	}
+	if r.isFunction && returnType == nil {
+		return nil, newErrSemantic(r.name, "function %v needs a result type", id)
+	}
+	sym := newRoutineSymbol(id, s.scope.level)
+	sym.params = params
+	sym.returnType = returnType
+	if r.isFunction {
+		sym.result = newVarSymbol(id, returnType, s.scope.level+1)
+	}
+	// The routine is declared before its body is analyzed so that it can
+	// call itself.
+	s.scope.insert(sym)
+	return sym, nil
+}
+
+func (s *semanticAnalyzer) VisitRoutineDeclNode(n node) (interface{}, error) {
+	r := n.(*routineDeclNode)
+	sym, err := s.routineHeading(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.block == nil {
+		return nil, nil
+	}
+	sym.block = r.block
+
+	s.scope = newScopedSymbolTable(sym.name, s.scope.level+1, s.scope)
+	s.routines = append(s.routines, sym)
+	defer func() {
+		s.scope = s.scope.enclosing
+		s.routines = s.routines[:len(s.routines)-1]
+	}()
+	for _, param := range sym.params {
+		s.scope.insert(param)
+	}
+	if sym.isFunction() && s.options.dialect.hasResult() {
+		if s.scope.lookup("result", true) != nil {
+			return nil, newErrSemantic(r.name, "duplicate identifier: result")
+		}
+		s.scope.insertAs("result", sym.result)
+	}
+	return s.visit(r.block)
+}
+
+// isAnalyzing returns true if the body of sym is being analyzed, which is
+// where its function result can be assigned to.
+func (s *semanticAnalyzer) isAnalyzing(sym *routineSymbol) bool {
+	for _, routine := range s.routines {
+		if routine == sym {
+			return true
+		}
+	}
+	return false
+}
+
+// routine looks up the declared or built-in routine called by name.
+func (s *semanticAnalyzer) routine(name *token) (symbol, error) {
+	id := name.value.(string)
+	sym := s.scope.lookup(id, false)
+	if sym == nil {
+		return nil, newErrUndefinedIdentifier(id)
+	}
+	switch sym.(type) {
+	case *routineSymbol, *builtinSymbol:
+		return sym, nil
+	}
+	return nil, newErrSemantic(name, "%v is not a procedure or function", id)
+}
+
+// call checks the arguments of a call of name against the formal
+// parameters. A VAR parameter must be passed a variable of exactly the
+// parameter's type, or any array of the element type if it is an open
+// array.
+func (s *semanticAnalyzer) call(params []*varSymbol, name *token, args []node) error {
+	if len(args) != len(params) {
+		return newErrSemantic(name, "%v expects %d arguments; got %d", name.value, len(params), len(args))
+	}
+	for index, param := range params {
+		arg := args[index]
+		typ, err := s.visitValue(arg, param.typ)
+		if err != nil {
+			return err
+		}
+		if !param.byRef {
+			if !isAssignable(param.typ, typ) {
+				return newErrSemantic(name, "cannot pass %v as %v parameter %v", typ, param.typ, param.name)
+			}
+			continue
+		}
+		if !isVariable(arg) {
+			return newErrSemantic(name, "VAR parameter %v must be passed a variable", param.name)
+		}
+		if !identicalTypes(typ, param.typ) && !(isOpenArray(param.typ) && isAssignable(param.typ, typ)) {
+			return newErrSemantic(name, "cannot pass %v as VAR %v parameter %v", typ, param.typ, param.name)
+		}
+		if v, ok := arg.(*varNode); ok && s.controlVariables[v.symbol] {
+			return newErrSemantic(v.t, "cannot pass FOR control variable %v as VAR parameter", v.symbol.name)
+		}
+	}
+	return nil
+}
+
+// callBuiltin analyzes the arguments of a call of a built-in routine and
+// lets the routine check them. It returns the result type, which is nil for
+// procedures, and the types of the arguments.
+func (s *semanticAnalyzer) callBuiltin(builtin *builtinSymbol, name *token, args []node) (dataType, []dataType, error) {
+	argTypes := make([]dataType, len(args))
+	for index, arg := range args {
+		var typ dataType
+		var err error
+		if f, ok := arg.(*formatNode); ok && builtin.formatted {
+			typ, err = s.format(f)
+		} else {
+			typ, err = s.visitExpr(arg)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		argTypes[index] = typ
+	}
+	typ, err := builtin.check(s, &builtinCall{name: name, args: args, argTypes: argTypes})
+	if err != nil {
+		return nil, nil, err
+	}
+	return typ, argTypes, nil
+}
+
+// format checks an argument with a field width and returns the type of its
+// value. Only real values may have a number of decimals.
+func (s *semanticAnalyzer) format(f *formatNode) (dataType, error) {
+	typ, err := s.visitExpr(f.value)
+	if err != nil {
+		return nil, err
+	}
+	width, err := s.visitExpr(f.width)
+	if err != nil {
+		return nil, err
+	}
+	if !isInteger(width) {
+		return nil, newErrSemantic(f.t, "field width must be an integer; got %v", width)
+	}
+	if f.decimals == nil {
+		return typ, nil
+	}
+	if !isReal(typ) && !isDecimal(typ) {
+		return nil, newErrSemantic(f.t, "only real and fixed-point values may have decimals; got %v", typ)
+	}
+	decimals, err := s.visitExpr(f.decimals)
+	if err != nil {
+		return nil, err
+	}
+	if !isInteger(decimals) {
+		return nil, newErrSemantic(f.t, "number of decimals must be an integer; got %v", decimals)
+	}
+	return typ, nil
+}
+
+func (s *semanticAnalyzer) VisitFormatNode(n node) (interface{}, error) {
+	r := n.(*formatNode)
+	return nil, newErrSemantic(r.t, "field widths are only allowed in Write and WriteLn")
+}
+
+func (s *semanticAnalyzer) VisitProcedureCallNode(n node) (interface{}, error) {
+	r := n.(*procedureCallNode)
+	variable, typ, err := s.procedureVariable(r.name, r.variable)
+	if err != nil {
+		return nil, err
+	}
+	if variable != nil {
+		if err = s.call(typ.params, r.name, r.args); err != nil {
+			return nil, err
+		}
+		if typ.returnType != nil {
+			return nil, newErrSemantic(r.name, "function %v cannot be called as a procedure", r.name.value)
+		}
+		r.variable = variable
+		return nil, nil
+	}
+	sym, err := s.routine(r.name)
+	if err != nil {
+		return nil, err
+	}
+	if builtin, ok := sym.(*builtinSymbol); ok {
+		typ, argTypes, err := s.callBuiltin(builtin, r.name, r.args)
+		if err != nil {
+			return nil, err
+		}
+		if typ != nil {
+			return nil, newErrSemantic(r.name, "function %v cannot be called as a procedure", builtin.name)
+		}
+		r.builtin, r.argTypes = builtin, argTypes
+		return nil, nil
+	}
+	routine := sym.(*routineSymbol)
+	if err = s.call(routine.params, r.name, r.args); err != nil {
+		return nil, err
+	}
+	if routine.isFunction() {
+		return nil, newErrSemantic(r.name, "function %v cannot be called as a procedure", routine.name)
+	}
+	r.symbol = routine
+	return nil, nil
+}
+
+func (s *semanticAnalyzer) VisitFunctionCallNode(n node) (interface{}, error) {
+	r := n.(*functionCallNode)
+	variable, typ, err := s.procedureVariable(r.name, r.variable)
+	if err != nil {
+		return nil, err
+	}
+	if variable != nil {
+		if err = s.call(typ.params, r.name, r.args); err != nil {
+			return nil, err
+		}
+		if typ.returnType == nil {
+			return nil, newErrSemantic(r.name, "procedure %v does not return a value", r.name.value)
+		}
+		r.variable = variable
+		return typ.returnType, nil
+	}
+	sym, err := s.routine(r.name)
+	if err != nil {
+		return nil, err
+	}
+	if builtin, ok := sym.(*builtinSymbol); ok {
+		typ, argTypes, err := s.callBuiltin(builtin, r.name, r.args)
+		if err != nil {
+			return nil, err
+		}
+		if typ == nil {
+			return nil, newErrSemantic(r.name, "procedure %v does not return a value", builtin.name)
+		}
+		r.builtin, r.argTypes = builtin, argTypes
+		return typ, nil
+	}
+	routine := sym.(*routineSymbol)
+	if err = s.call(routine.params, r.name, r.args); err != nil {
+		return nil, err
+	}
+	if !routine.isFunction() {
+		return nil, newErrSemantic(r.name, "procedure %v does not return a value", routine.name)
+	}
+	r.symbol = routine
+	return routine.returnType, nil
+}
+
+// procedureVariable analyzes the variable a call of name goes through. That
+// is designator if the parser found a component such as r.handler, or name
+// itself if it denotes a variable or a WITH field of a procedural type. It
+// returns a nil node if name denotes something else, such as a routine.
+func (s *semanticAnalyzer) procedureVariable(name *token, designator node) (node, *procedureType, error) {
+	id := name.value.(string)
+	variable := designator
+	if variable == nil {
+		if _, field := s.withField(id); field != nil {
+			if _, ok := field.typ.(*procedureType); !ok {
+				return nil, nil, nil
+			}
+		} else if _, ok := s.scope.lookup(id, false).(*varSymbol); !ok {
+			return nil, nil, nil
+		}
+		variable = newVarNode(name)
+	}
+	typ, err := s.visitExpr(variable)
+	if err != nil {
+		return nil, nil, err
+	}
+	procedure, ok := typ.(*procedureType)
+	if !ok {
+		return nil, nil, newErrSemantic(name, "%v is not a procedure or function", id)
+	}
+	return variable, procedure, nil
+}
+
+// visitValue analyzes an expression whose value is assigned or passed to a
+// variable of type target. If target is a procedural type, the name of a
+// routine denotes the routine itself rather than a call of it.
+func (s *semanticAnalyzer) visitValue(n node, target dataType) (dataType, error) {
+	markExact(n, target)
+	if v, ok := n.(*varNode); ok && isProcedural(target) {
+		id := v.t.value.(string)
+		_, field := s.withField(id)
+		switch s.scope.lookup(id, false).(type) {
+		case *routineSymbol, *builtinSymbol:
+			if field == nil {
+				return s.routineValue(v)
+			}
+		}
+	}
+	typ, err := s.visitExpr(n)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.checkSetElements(n, target); err != nil {
+		return nil, err
+	}
+	return typ, nil
+}
+
+// checkSetElements checks that the constant elements of a set constructor
+// assigned to a set of type target are within its base type, such as 20 in
+// [20] for a SET OF 0..10.
+func (s *semanticAnalyzer) checkSetElements(n node, target dataType) error {
+	set, ok := n.(*setNode)
+	targetSet, isSet := baseType(target).(*setType)
+	if !ok || !isSet || targetSet.base == nil {
+		return nil
+	}
+	for _, element := range set.elements {
+		bounds := []node{element}
+		if r, ok := element.(*rangeNode); ok {
+			bounds = []node{r.low, r.high}
+		}
+		for _, bound := range bounds {
+			value, ok := s.foldedValue(bound)
+			if !ok {
+				continue
+			}
+			if err := checkConstantRange(nodeToken(bound), targetSet.base, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// markExact makes a real literal, possibly signed, that is assigned to a
+// CURRENCY or DECIMAL target keep all of its digits, even those a real does
+// not hold.
+func markExact(n node, target dataType) {
+	if _, ok := baseType(target).(*decimalType); !ok {
+		return
+	}
+	switch r := n.(type) {
+	case *unaryNode:
+		if r.t.tokenType != tokenTypeNot {
+			markExact(r.child, target)
+		}
+	case *valueNode:
+		_, r.exact = r.t.value.(decimal)
+	}
+}
+
+// routineValue analyzes the name of a routine that is taken as a value.
+func (s *semanticAnalyzer) routineValue(v *varNode) (dataType, error) {
+	id := v.t.value.(string)
+	switch sym := s.scope.lookup(id, false).(type) {
+	case nil:
+		return nil, newErrUndefinedIdentifier(id)
+	case *routineSymbol:
+		v.routine = sym
+		return sym.procedureType(), nil
+	case *builtinSymbol:
+		return nil, newErrSemantic(v.t, "standard routine %v cannot be taken as a value", id)
+	}
+	return nil, newErrSemantic(v.t, "%v is not a procedure or function", id)
+}
+
+func (s *semanticAnalyzer) VisitAddressNode(n node) (interface{}, error) {
+	r := n.(*addressNode)
+	return s.routineValue(r.routine.(*varNode))
+}
+
+// withField looks up a field of the records opened by WITH statements.
+func (s *semanticAnalyzer) withField(name string) (*withRecord, *fieldSymbol) {
+	for index := len(s.withs) - 1; index >= 0; index-- {
+		if field := s.withs[index].typ.field(name); field != nil {
+			return s.withs[index], field
+		}
+	}
+	return nil, nil
+}
+
+func (s *semanticAnalyzer) VisitVarNode(n node) (interface{}, error) {
+	r := n.(*varNode)
+	id := r.t.value.(string)
+	if with, field := s.withField(id); field != nil {
+		r.field = &fieldNode{
+			t:      r.t,
+			record: &varNode{t: r.t, symbol: with.symbol},
+			symbol: field,
+		}
+		return field.typ, nil
+	}
+	sym := s.scope.lookup(id, false)
+	if sym == nil {
+		return nil, newErrUndefinedIdentifier(id)
+	}
+	if c, ok := sym.(*constSymbol); ok {
+		r.constant = c
+		return c.typ, nil
+	}
+	if routine, ok := sym.(*routineSymbol); ok && routine.isFunction() {
+		if r.symbol == routine.result {
+			return r.symbol.typ, nil
+		}
+		r.call = newFunctionCallNode(r.t, nil, switches{})
+		return s.visit(r.call)
+	}
+	if _, ok := sym.(*builtinSymbol); ok {
+		r.call = newFunctionCallNode(r.t, nil, switches{})
+		return s.visit(r.call)
+	}
+	v, ok := sym.(*varSymbol)
+	if !ok {
+		return nil, newErrSemantic(r.t, "%v is not a variable", id)
+	}
+	r.symbol = v
+	return v.typ, nil
+}
+
+func (s *semanticAnalyzer) VisitTypeNode(n node) (interface{}, error) {
+	r := n.(*typeNode)
+	switch r.t.tokenType {
+	case tokenTypeInteger:
+		return s.options.integer(), nil
+	case tokenTypeReal:
+		return typeReal, nil
+	case tokenTypeBoolean:
+		return typeBoolean, nil
+	case tokenTypeChar:
+		return typeChar, nil
+	case tokenTypeString:
+		return typeString, nil
+	}
+	id := r.t.value.(string)
+	sym := s.scope.lookup(id, false)
+	if sym == nil {
+		return nil, newErrUndefinedIdentifier(id)
+	}
+	t, ok := sym.(*typeSymbol)
+	if !ok {
+		return nil, newErrSemantic(r.t, "%v is not a type", id)
+	}
+	return t.typ, nil
+}
+
+func (s *semanticAnalyzer) VisitTypeDeclNode(n node) (interface{}, error) {
+	r := n.(*typeDeclNode)
+	id := r.name.value.(string)
+	if s.scope.lookup(id, true) != nil {
+		return nil, newErrSemantic(r.name, "duplicate identifier: %v", id)
+	}
+	typ, err := s.visitExpr(r.typeNode)
+	if err != nil {
+		return nil, err
+	}
+	nameType(typ, id)
+	s.scope.insert(newTypeSymbol(id, typ))
+	return nil, nil
+}
+
+// VisitEnumTypeNode declares the values of an enumeration as constants of
+// the new type.
+func (s *semanticAnalyzer) VisitEnumTypeNode(n node) (interface{}, error) {
+	r := n.(*enumTypeNode)
+	if r.typ != nil {
+		return r.typ, nil
+	}
+	values := make([]string, len(r.values))
+	for index, value := range r.values {
+		values[index] = value.value.(string)
+	}
+	typ := newEnumType(values)
+	for ord, value := range r.values {
+		id := value.value.(string)
+		if s.scope.lookup(id, true) != nil {
+			return nil, newErrSemantic(value, "duplicate identifier: %v", id)
+		}
+		s.scope.insert(newConstSymbol(id, typ, ord))
+	}
+	r.typ = typ
+	return typ, nil
+}
+
+func (s *semanticAnalyzer) VisitSubrangeTypeNode(n node) (interface{}, error) {
+	r := n.(*subrangeTypeNode)
+	lowType, low, err := s.constantExpr(r.low)
+	if err != nil {
+		return nil, err
+	}
+	highType, high, err := s.constantExpr(r.high)
+	if err != nil {
+		return nil, err
+	}
+	if !isOrdinal(lowType) || !sameOrdinalBase(lowType, highType) {
+		return nil, newErrSemantic(r.t, "invalid subrange bounds: %v and %v", lowType, highType)
+	}
+	base := baseType(lowType)
+	if isInteger(base) {
+		base = commonInteger([]*integerType{base.(*integerType), baseType(highType).(*integerType)}, lowType, highType)
+	}
+	bounds := ordinalRange{
+		low:  ordinalValue(low),
+		high: ordinalValue(high),
+	}
+	if bounds.low > bounds.high {
+		return nil, newErrSemantic(r.t, "empty subrange")
+	}
+	return newSubrangeType(base, bounds), nil
+}
+
+// checkConstantRange checks that a constant fits into the bounds of an
+// ordinal type, or into the base type of a set type. Unlike values computed
+// at run time, constants are checked even if range checks are off.
+func checkConstantRange(t *token, typ dataType, value interface{}) error {
+	if members, ok := value.(set); ok {
+		if setT, ok := typ.(*setType); ok && setT.base != nil {
+			if ord, ok := elementOutOfRange(setT, members); ok {
+				return newErrSemantic(t, "constant %v is out of range for %v", ordinalString(setT.base, ord), setT.base)
+			}
+		}
+		return nil
+	}
+	if checkRange(typ, value) != nil {
+		return newErrSemantic(t, "constant %v is out of range for %v", ordinalString(typ, ordinalValue(value)), typ)
+	}
+	return nil
+}
+
+// maxArrayLength is the largest number of elements of an array type.
+const maxArrayLength = 1 << 24
+
+// VisitArrayTypeNode checks that the index type of an array is an ordinal
+// type with at most maxArrayLength values.
+func (s *semanticAnalyzer) VisitArrayTypeNode(n node) (interface{}, error) {
+	r := n.(*arrayTypeNode)
+	if r.typ != nil {
+		return r.typ, nil
+	}
+	if r.indexType == nil {
+		element, err := s.visitExpr(r.elementType)
+		if err != nil {
+			return nil, err
+		}
+		r.typ = newDynamicArrayType(element)
+		return r.typ, nil
+	}
+	index, err := s.visitExpr(r.indexType)
+	if err != nil {
+		return nil, err
+	}
+	bounds, ok := ordinalBounds(index)
+	if !ok {
+		return nil, newErrSemantic(r.t, "array index type must be a bounded ordinal type; got %v", index)
+	}
+	// The difference of the bounds of INT64 overflows to -1, which is
+	// rejected as well.
+	if uint64(bounds.high-bounds.low) >= maxArrayLength {
+		return nil, newErrSemantic(r.t, "array index type %v has more than %d values", index, maxArrayLength)
+	}
+	element, err := s.visitExpr(r.elementType)
+	if err != nil {
+		return nil, err
+	}
+	r.typ = newArrayType(index, element)
+	return r.typ, nil
+}
+
+func (s *semanticAnalyzer) VisitIndexNode(n node) (interface{}, error) {
+	r := n.(*indexNode)
+	typ, err := s.visitExpr(r.array)
+	if err != nil {
+		return nil, err
+	}
+	index, err := s.visitExpr(r.index)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := typ.(*stringType); ok {
+		if !isInteger(index) {
+			return nil, newErrSemantic(r.t, "string index must be an integer; got %v", index)
+		}
+		r.typ = typ
+		return typeChar, nil
+	}
+	if dynamic, ok := typ.(*dynamicArrayType); ok {
+		if !isInteger(index) {
+			return nil, newErrSemantic(r.t, "array index must be an integer; got %v", index)
+		}
+		r.typ = dynamic
+		return dynamic.element, nil
+	}
+	array, ok := typ.(*arrayType)
+	if !ok {
+		return nil, newErrSemantic(r.t, "cannot index %v", typ)
+	}
+	if !isAssignable(array.index, index) {
+		return nil, newErrSemantic(r.t, "array index must be %v; got %v", array.index, index)
+	}
+	r.typ = array
+	return array.element, nil
+}
+
+func (s *semanticAnalyzer) VisitRecordTypeNode(n node) (interface{}, error) {
+	r := n.(*recordTypeNode)
+	if r.typ != nil {
+		return r.typ, nil
+	}
+	typ := newRecordType()
+	if err := s.fieldList(typ, r.fields.(*fieldListNode)); err != nil {
+		return nil, err
+	}
+	r.typ = typ
+	return typ, nil
+}
+
+// fieldList adds the fields of a record or of a record variant to typ.
+func (s *semanticAnalyzer) fieldList(typ *recordType, r *fieldListNode) error {
+	for _, f := range r.fields {
+		decl := f.(*fieldDeclNode)
+		fieldType, err := s.visitExpr(decl.typeNode)
+		if err != nil {
+			return err
+		}
+		for _, name := range decl.names {
+			if err = s.addField(typ, name, fieldType); err != nil {
+				return err
+			}
+		}
+	}
+	if r.variant == nil {
+		return nil
+	}
+
+	variant := r.variant.(*variantPartNode)
+	tagType, err := s.visitExpr(variant.tagType)
+	if err != nil {
+		return err
+	}
+	if !isOrdinal(tagType) {
+		return newErrSemantic(variant.t, "variant tag must be of an ordinal type; got %v", tagType)
+	}
+	if variant.tag != nil {
+		if err = s.addField(typ, variant.tag, tagType); err != nil {
+			return err
+		}
+	}
+	for _, v := range variant.variants {
+		v := v.(*variantNode)
+		for _, label := range v.labels {
+			if _, err = s.caseLabelRange(label, tagType); err != nil {
+				return err
+			}
+		}
+		if err = s.fieldList(typ, v.fields.(*fieldListNode)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *semanticAnalyzer) addField(typ *recordType, name *token, fieldType dataType) error {
+	id := name.value.(string)
+	if typ.field(id) != nil {
+		return newErrSemantic(name, "duplicate field: %v", id)
+	}
+	typ.addField(id, fieldType)
+	return nil
+}
+
+func (s *semanticAnalyzer) VisitStringTypeNode(n node) (interface{}, error) {
+	r := n.(*stringTypeNode)
+	typ, size, err := s.constantExpr(r.size)
+	if err != nil {
+		return nil, err
+	}
+	if !isInteger(typ) || ordinalValue(size) < 1 || ordinalValue(size) > maxStringSize {
+		return nil, newErrSemantic(r.t, "string size must be an integer in 1..%d", maxStringSize)
+	}
+	return newStringType(ordinalValue(size)), nil
+}
+
+// VisitSetTypeNode checks that the ordinal numbers of the base type fit into
+// a set.
+func (s *semanticAnalyzer) VisitSetTypeNode(n node) (interface{}, error) {
+	r := n.(*setTypeNode)
+	base, err := s.visitExpr(r.baseType)
+	if err != nil {
+		return nil, err
+	}
+	bounds, ok := ordinalBounds(base)
+	if !ok || bounds.low < 0 || bounds.high > maxSetOrdinal {
+		return nil, newErrSemantic(r.t, "set base type must be an ordinal type with values in 0..%d; got %v", maxSetOrdinal, base)
+	}
+	return newSetType(base), nil
+}
+
+func (s *semanticAnalyzer) VisitFileTypeNode(n node) (interface{}, error) {
+	r := n.(*fileTypeNode)
+	element, err := s.visitExpr(r.elementType)
+	if err != nil {
+		return nil, err
+	}
+	if !isStorable(element) {
+		return nil, newErrSemantic(r.t, "files cannot hold %v", element)
+	}
+	return newFileType(element), nil
+}
+
+// VisitPointerTypeNode creates a pointer type. If the target type is not
+// declared yet, it is resolved at the end of the TYPE section.
+func (s *semanticAnalyzer) VisitPointerTypeNode(n node) (interface{}, error) {
+	r := n.(*pointerTypeNode)
+	target := r.target.(*typeNode).t
+	if target.tokenType == tokenTypeID && s.scope.lookup(target.value.(string), false) == nil {
+		typ := newPointerType(nil)
+		s.pointers = append(s.pointers, &forwardPointer{typ: typ, target: target})
+		return typ, nil
+	}
+	typ, err := s.visitExpr(r.target)
+	if err != nil {
+		return nil, err
+	}
+	return newPointerType(typ), nil
+}
+
+// VisitProcedureTypeNode analyzes a procedural type. Only the types of its
+// parameters matter; their names document the type.
+func (s *semanticAnalyzer) VisitProcedureTypeNode(n node) (interface{}, error) {
+	r := n.(*procedureTypeNode)
+	params, err := s.formalParameters(r.params)
+	if err != nil {
+		return nil, err
+	}
+	var returnType dataType
+	if r.returnType != nil {
+		if returnType, err = s.visitExpr(r.returnType); err != nil {
+			return nil, err
+		}
+	}
+	return newProcedureType(params, returnType), nil
+}
+
+func (s *semanticAnalyzer) resolvePointers() error {
+	for _, pointer := range s.pointers {
+		typ, err := s.visitExpr(newTypeNode(pointer.target))
+		if err != nil {
+			return err
+		}
+		pointer.typ.target = typ
+	}
+	s.pointers = nil
+	return nil
+}
+
+func (s *semanticAnalyzer) VisitDerefNode(n node) (interface{}, error) {
+	r := n.(*derefNode)
+	typ, err := s.visitExpr(r.pointer)
+	if err != nil {
+		return nil, err
+	}
+	pointer, ok := typ.(*pointerType)
+	if !ok || pointer == typeNil {
+		return nil, newErrSemantic(r.t, "cannot dereference %v", typ)
+	}
+	return pointer.target, nil
+}
+
+func (s *semanticAnalyzer) VisitFieldNode(n node) (interface{}, error) {
+	r := n.(*fieldNode)
+	typ, err := s.visitExpr(r.record)
+	if err != nil {
+		return nil, err
+	}
+	record, ok := typ.(*recordType)
+	if !ok {
+		return nil, newErrSemantic(r.t, "%v is not a record", typ)
+	}
+	id := r.t.value.(string)
+	if r.symbol = record.field(id); r.symbol == nil {
+		return nil, newErrSemantic(r.t, "%v has no field %v", record, id)
+	}
+	return r.symbol.typ, nil
+}
+
+// isVariable returns true if an analyzed expression denotes a variable,
+// i.e. it can be assigned to or passed as a VAR parameter.
+func isVariable(n node) bool {
+	switch r := n.(type) {
+	case *varNode:
+		if r.field != nil {
+			return isVariable(r.field)
+		}
+		return r.symbol != nil
+	case *indexNode:
+		return isVariable(r.array)
+	case *fieldNode:
+		return isVariable(r.record)
+	case *derefNode:
+		return true
+	}
+	return false
+}
+
+// resolveResult resolves the variable a designator starts with to the result
+// of the function of that name if its body is being analyzed, so that fields
+// and elements of the result can be assigned to instead of calling it.
+func (s *semanticAnalyzer) resolveResult(n node) {
+	switch r := n.(type) {
+	case *indexNode:
+		s.resolveResult(r.array)
+	case *fieldNode:
+		s.resolveResult(r.record)
+	case *varNode:
+		id := r.t.value.(string)
+		if _, field := s.withField(id); field != nil {
+			return
+		}
+		if routine, ok := s.scope.lookup(id, false).(*routineSymbol); ok && routine.isFunction() && s.isAnalyzing(routine) {
+			r.symbol = routine.result
+		}
+	}
+}
+
+// declareImplicitly declares an undeclared variable in the current scope.
+func (s *semanticAnalyzer) declareImplicitly(v *varNode, typ dataType) {
+	v.symbol = newVarSymbol(v.t.value.(string), typ, s.scope.level)
+	s.scope.insert(v.symbol)
+}
+
+func (s *semanticAnalyzer) VisitAssignNode(n node) (interface{}, error) {
+	r := n.(*assignNode)
+	left, ok := r.left.(*varNode)
+	if _, field := s.withField(r.t.value.(string)); !ok || field != nil {
+		s.resolveResult(r.left)
+		typ, err := s.visitExpr(r.left)
+		if err != nil {
+			return nil, err
+		}
+		if !isVariable(r.left) {
+			return nil, newErrSemantic(r.t, "cannot assign to %v", r.t.value)
+		}
+		right, err := s.visitValue(r.right, typ)
+		if err != nil {
+			return nil, err
+		}
+		if !isAssignable(typ, right) {
+			return nil, newErrSemantic(r.t, "cannot assign %v to %v", right, typ)
+		}
+		r.typ = typ
+		return nil, nil
+	}
+	sym := s.scope.lookup(left.t.value.(string), false)
+	if sym == nil {
+		right, err := s.visitExpr(r.right)
+		if err != nil {
+			return nil, err
+		}
+		s.declareImplicitly(left, right)
+		r.typ = right
+		return nil, nil
+	}
+	if routine, ok := sym.(*routineSymbol); ok && routine.isFunction() && s.isAnalyzing(routine) {
+		left.symbol = routine.result
+	} else if _, err := s.visit(left); err != nil {
+		return nil, err
+	}
+	if left.constant != nil {
+		return nil, newErrSemantic(left.t, "cannot assign to constant %v", left.constant.name)
+	}
+	if left.symbol == nil {
+		return nil, newErrSemantic(left.t, "cannot assign to %v", left.t.value)
+	}
+	if s.controlVariables[left.symbol] {
+		return nil, newErrSemantic(left.t, "cannot assign to FOR control variable %v", left.symbol.name)
+	}
+	if isOpenArray(left.symbol.typ) {
+		return nil, newErrSemantic(left.t, "cannot assign to open array %v", left.symbol.name)
+	}
+	right, err := s.visitValue(r.right, left.symbol.typ)
+	if err != nil {
+		return nil, err
+	}
+	if !isAssignable(left.symbol.typ, right) {
+		return nil, newErrSemantic(left.t, "cannot assign %v to %v variable %v", right, left.symbol.typ, left.symbol.name)
+	}
+	r.typ = left.symbol.typ
+	return nil, nil
+}
+
+func (s *semanticAnalyzer) VisitIfNode(n node) (interface{}, error) {
+	r := n.(*ifNode)
+	if err := s.visitCondition(r.condition); err != nil {
+		return nil, err
+	}
+	if _, err := s.visit(r.thenNode); err != nil {
+		return nil, err
+	}
+	if r.elseNode != nil {
+		return s.visit(r.elseNode)
+	}
+	return nil, nil
+}
+
+// VisitWithNode opens the records of a WITH statement one after the other, so
+// that WITH a, b DO is the same as WITH a DO WITH b DO.
+func (s *semanticAnalyzer) VisitWithNode(n node) (interface{}, error) {
+	r := n.(*withNode)
+	depth := len(s.withs)
+	defer func() {
+		s.withs = s.withs[:depth]
+	}()
+	r.symbols = nil
+	for _, record := range r.records {
+		typ, err := s.visitExpr(record)
+		if err != nil {
+			return nil, err
+		}
+		recordType, ok := typ.(*recordType)
+		if !ok {
+			return nil, newErrSemantic(nodeToken(record), "WITH needs a record; got %v", typ)
+		}
+		// The space keeps the name of the hidden variable apart from
+		// identifiers.
+		s.withCount++
+		sym := newVarSymbol(fmt.Sprintf("with %d", s.withCount), recordType, s.scope.level)
+		sym.byRef = isVariable(record)
+		r.symbols = append(r.symbols, sym)
+		s.withs = append(s.withs, &withRecord{typ: recordType, symbol: sym})
+	}
+	return s.visit(r.body)
+}
+
+func (s *semanticAnalyzer) VisitWhileNode(n node) (interface{}, error) {
+	r := n.(*whileNode)
+	if err := s.visitCondition(r.condition); err != nil {
+		return nil, err
+	}
+	return s.visit(r.body)
+}
+
+func (s *semanticAnalyzer) VisitRepeatNode(n node) (interface{}, error) {
+	r := n.(*repeatNode)
+	if err := s.visitStatements(r.children); err != nil {
+		return nil, err
+	}
+	return nil, s.visitCondition(r.condition)
+}
+
+// VisitForNode checks the ISO 7185 rules for FOR statements: the control
+// variable is an ordinal variable declared in the current block, and it is
+// not assigned to in the body.
+func (s *semanticAnalyzer) VisitForNode(n node) (interface{}, error) {
+	r := n.(*forNode)
+	start, err := s.visitExpr(r.start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := s.visitExpr(r.end)
+	if err != nil {
+		return nil, err
+	}
+
+	variable := r.variable.(*varNode)
+	id := variable.t.value.(string)
+	if _, field := s.withField(id); field != nil {
+		return nil, newErrSemantic(variable.t, "FOR control variable %v must be a local variable", id)
+	}
+	switch {
+	case s.scope.lookup(id, true) != nil:
+		if _, err = s.visit(variable); err != nil {
+			return nil, err
+		}
+	case s.scope.lookup(id, false) != nil:
+		return nil, newErrSemantic(variable.t, "FOR control variable %v must be a local variable", id)
+	default:
+		s.declareImplicitly(variable, start)
+	}
+	sym := variable.symbol
+	if sym == nil {
+		return nil, newErrSemantic(variable.t, "%v is not a variable", id)
+	}
+	if sym.isParam {
+		return nil, newErrSemantic(variable.t, "FOR control variable %v must be a local variable", id)
+	}
+	if !isOrdinal(sym.typ) {
+		return nil, newErrSemantic(variable.t, "FOR control variable %v must be of an ordinal type; got %v", id, sym.typ)
+	}
+	if s.controlVariables[sym] {
+		return nil, newErrSemantic(variable.t, "cannot assign to FOR control variable %v", id)
+	}
+	if !isAssignable(sym.typ, start) || !isAssignable(sym.typ, end) {
+		return nil, newErrSemantic(variable.t, "FOR bounds must be of type %v; got %v and %v", sym.typ, start, end)
+	}
+
+	s.controlVariables[sym] = true
+	defer delete(s.controlVariables, sym)
+	return s.visit(r.body)
+}
+
+// isConstant returns true if an analyzed expression can be evaluated at
+// compile time.
+func isConstant(n node) bool {
+	switch r := n.(type) {
+	case *valueNode:
+		return true
+	case *varNode:
+		return r.constant != nil
+	case *unaryNode:
+		return isConstant(r.child)
+	case *binaryNode:
+		return isConstant(r.left) && isConstant(r.right)
+	case *rangeNode:
+		return isConstant(r.low) && isConstant(r.high)
+	case *setNode:
+		for _, element := range r.elements {
+			if !isConstant(element) {
+				return false
+			}
+		}
+		return true
+	case *functionCallNode:
+		if r.builtin == nil || !r.builtin.pure {
+			return false
+		}
+		for _, arg := range r.args {
+			if !isConstant(arg) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// foldedValue returns the value of an analyzed expression if it is constant
+// and can be evaluated without errors, so that values known at compile time
+// can be checked.
+func (s *semanticAnalyzer) foldedValue(n node) (interface{}, bool) {
+	if !isConstant(n) {
+		return nil, false
+	}
+	value, err := evaluateConstant(s.options, n)
+	return value, err == nil
+}
+
+// constantExpr analyzes an expression that must be constant and folds it.
+func (s *semanticAnalyzer) constantExpr(n node) (dataType, interface{}, error) {
+	typ, err := s.visitExpr(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isConstant(n) {
+		return nil, nil, newErrSemantic(nodeToken(n), "constant expression expected")
+	}
+	value, err := evaluateConstant(s.options, n)
+	if err != nil {
+		// Errors in constant expressions are found while compiling, so
+		// they are not runtime errors.
+		if runtimeErr, ok := err.(*errRuntime); ok {
+			return nil, nil, newErrSemantic(nodeToken(n), "%v", runtimeErr.msg)
+		}
+		return nil, nil, err
+	}
+	return typ, value, nil
+}
+
+// caseLabelRange checks a case label against the type of the selector and
+// returns the ordinal values it covers.
+func (s *semanticAnalyzer) caseLabelRange(label node, selector dataType) (ordinalRange, error) {
+	low, high := label, label
+	t := nodeToken(label)
+	if r, ok := label.(*rangeNode); ok {
+		low, high = r.low, r.high
+	}
+	var values [2]interface{}
+	for index, bound := range []node{low, high} {
+		typ, value, err := s.constantExpr(bound)
+		if err != nil {
+			return ordinalRange{}, err
+		}
+		if !isAssignable(selector, typ) {
+			return ordinalRange{}, newErrSemantic(t, "case label must be %v; got %v", selector, typ)
+		}
+		values[index] = value
+	}
+	r := ordinalRange{
+		low:  ordinalValue(values[0]),
+		high: ordinalValue(values[1]),
+	}
+	if r.low > r.high {
+		return ordinalRange{}, newErrSemantic(t, "empty case label range")
+	}
+	return r, nil
+}
+
+// VisitCaseNode checks that the selector is ordinal and that no value is
+// covered by more than one label.
+func (s *semanticAnalyzer) VisitCaseNode(n node) (interface{}, error) {
+	r := n.(*caseNode)
+	selector, err := s.visitExpr(r.selector)
+	if err != nil {
+		return nil, err
+	}
+	if !isOrdinal(selector) {
+		return nil, newErrSemantic(r.t, "case selector must be of an ordinal type; got %v", selector)
+	}
+
+	var seen []ordinalRange
+	for _, b := range r.branches {
+		branch := b.(*caseBranchNode)
+		branch.ranges = nil
+		for _, label := range branch.labels {
+			labelRange, err := s.caseLabelRange(label, selector)
+			if err != nil {
+				return nil, err
+			}
+			for _, other := range seen {
+				if labelRange.overlaps(other) {
+					return nil, newErrSemantic(nodeToken(label), "duplicate case label")
+				}
+			}
+			seen = append(seen, labelRange)
+			branch.ranges = append(branch.ranges, labelRange)
+		}
+		if _, err = s.visit(branch.body); err != nil {
+			return nil, err
+		}
+	}
+	if r.elseNode != nil {
+		return s.visit(r.elseNode)
+	}
+	return nil, nil
+}
+
+// nodeToken returns the token an expression node was created for, if any.
+func nodeToken(n node) *token {
+	switch r := n.(type) {
+	case *rangeNode:
+		return r.t
+	case *binaryNode:
+		return r.t
+	case *unaryNode:
+		return r.t
+	case *valueNode:
+		return r.t
+	case *varNode:
+		return r.t
+	case *functionCallNode:
+		return r.name
+	case *indexNode:
+		return nodeToken(r.array)
+	case *fieldNode:
+		return r.t
+	case *derefNode:
+		return r.t
+	case *setNode:
+		return r.t
+	case *addressNode:
+		return r.t
+	}
+	return nil
+}
+
+// VisitSetNode checks that the elements of a set constructor are of the same
+// ordinal type. The type of the empty set has no base type.
+func (s *semanticAnalyzer) VisitSetNode(n node) (interface{}, error) {
+	r := n.(*setNode)
+	var base dataType
+	for _, element := range r.elements {
+		bounds := []node{element}
+		if rangeNode, ok := element.(*rangeNode); ok {
+			bounds = []node{rangeNode.low, rangeNode.high}
+		}
+		for _, bound := range bounds {
+			typ, err := s.visitExpr(bound)
+			if err != nil {
+				return nil, err
+			}
+			if !isOrdinal(typ) || base != nil && !sameOrdinalBase(typ, base) {
+				return nil, newErrSemantic(r.t, "invalid set element of type %v", typ)
+			}
+			base = baseType(typ)
+		}
+	}
+	return newSetType(base), nil
+}
+
+func (s *semanticAnalyzer) VisitBinaryNode(n node) (interface{}, error) {
+	r := n.(*binaryNode)
+	left, err := s.visitExpr(r.left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := s.visitExpr(r.right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := r.t.tokenType; {
+	case t == tokenTypeIn:
+		if set, ok := right.(*setType); ok && isOrdinal(left) && (set.base == nil || sameOrdinalBase(set.base, left)) {
+			return typeBoolean, nil
+		}
+	case (t == tokenTypeEqual || t == tokenTypeNotEqual) && (compatiblePointers(left, right) || compatibleProcedures(left, right) || arrayAndNil(left, right)):
+		return typeBoolean, nil
+	case (t == tokenTypeEqual || t == tokenTypeNotEqual) && complexOperands(left, right):
+		return typeBoolean, nil
+	case isRelationalOperator(t) && compatibleSets(left, right):
+		if t != tokenTypeLess && t != tokenTypeGreater {
+			return typeBoolean, nil
+		}
+	case (t == tokenTypePlus || t == tokenTypeMinus || t == tokenTypeMul) && compatibleSets(left, right):
+		if left.(*setType).base == nil {
+			return right, nil
+		}
+		return left, nil
+	case t == tokenTypeAnd || t == tokenTypeOr || t == tokenTypeXor:
+		if isBoolean(left) && isBoolean(right) {
+			return typeBoolean, nil
+		}
+		if isInteger(left) && isInteger(right) {
+			return s.integerResult(r, left, right), nil
+		}
+	case isRelationalOperator(t):
+		if isNumeric(left) && isNumeric(right) || isOrdinal(left) && sameOrdinalBase(left, right) || isText(left) && isText(right) {
+			return typeBoolean, nil
+		}
+	case t == tokenTypeDivInteger || t == tokenTypeMod || t == tokenTypeShl || t == tokenTypeShr:
+		if isInteger(left) && isInteger(right) {
+			return s.integerResult(r, left, right), nil
+		}
+	case t == tokenTypeDivReal:
+		if complexOperands(left, right) {
+			return s.complexResult(r), nil
+		}
+		if isNumeric(left) && isNumeric(right) {
+			return s.realResult(r, left, right), nil
+		}
+	default:
+		if t == tokenTypePlus && isText(left) && isText(right) {
+			return typeString, nil
+		}
+		if (t == tokenTypePlus || t == tokenTypeMinus || t == tokenTypeMul) && complexOperands(left, right) {
+			return s.complexResult(r), nil
+		}
+		if isInteger(left) && isInteger(right) {
+			return s.integerResult(r, left, right), nil
+		}
+		if isNumeric(left) && isNumeric(right) {
+			return s.realResult(r, left, right), nil
+		}
+	}
+	return nil, newErrSemantic(r.t, "invalid operands for %v: %v and %v", r.t.tokenType, left, right)
+}
+
+// integerResult returns the type an operation on integers of types a and b
+// is evaluated in, and records it in the node so that the result can wrap
+// around to it.
+func (s *semanticAnalyzer) integerResult(r *binaryNode, a, b dataType) dataType {
+	r.typ = commonInteger(s.options.evaluationTypes(), a, b)
+	return r.typ
+}
+
+// realResult returns the type an operation on numbers of types a and b, of
+// which at least one is not an integer, is evaluated in, and records it in
+// the node so that the result can be rounded to it. Operations on SINGLE and
+// integers are evaluated in single precision, and other ones with a real in
+// double precision. Operations on fixed-point numbers are evaluated in
+// DECIMAL if one of the operands is DECIMAL, and in CURRENCY otherwise, even
+// if the other operand is real, so that 0.015 is exactly 0.015.
+func (s *semanticAnalyzer) realResult(r *binaryNode, a, b dataType) dataType {
+	if t := decimalResult(a, b); t != nil {
+		r.typ = t
+		return r.typ
+	}
+	r.typ = typeReal
+	if a == typeSingle && (b == typeSingle || isInteger(b)) || b == typeSingle && isInteger(a) {
+		r.typ = typeSingle
+	}
+	return r.typ
+}
+
+// complexResult records in the node that an operation is evaluated in
+// complex arithmetic, and returns COMPLEX.
+func (s *semanticAnalyzer) complexResult(r *binaryNode) dataType {
+	r.typ = typeComplex
+	return r.typ
+}
+
+// decimalResult returns the fixed-point type an operation on numbers of
+// types a and b is evaluated in, or nil if it is not a fixed-point
+// operation.
+func decimalResult(a, b dataType) *decimalType {
+	if !isDecimal(a) && !isDecimal(b) {
+		return nil
+	}
+	if a == typeDecimal || b == typeDecimal {
+		return typeDecimal
+	}
+	return typeCurrency
+}
+
+func (s *semanticAnalyzer) VisitUnaryNode(n node) (interface{}, error) {
+	r := n.(*unaryNode)
+	child, err := s.visitExpr(r.child)
+	if err != nil {
+		return nil, err
+	}
+	if r.t.tokenType == tokenTypeMinus && isInteger(child) {
+		r.typ = commonInteger(s.options.evaluationTypes(), child, child)
+		return r.typ, nil
+	}
+	if r.t.tokenType == tokenTypeNot && (isBoolean(child) || isInteger(child)) || r.t.tokenType != tokenTypeNot && (isNumeric(child) || isComplex(child)) {
+		r.typ = baseType(child)
+		return r.typ, nil
+	}
+	return nil, newErrSemantic(r.t, "invalid operand for %v: %v", r.t.tokenType, child)
+}
+
+func (s *semanticAnalyzer) VisitValueNode(n node) (interface{}, error) {
+	r := n.(*valueNode)
+	switch r.t.tokenType {
+	case tokenTypeIntegerConst:
+		// An integer literal has the smallest of the types INTEGER, LONGINT
+		// and INT64 that holds it. Literals too large for INT64 are only
+		// valid if INTEGER has no bounds.
+		integer := s.options.integer()
+		value, ok := r.t.value.(int)
+		if !ok {
+			if integer.unbounded() {
+				return integer, nil
+			}
+			return nil, newErrSemantic(r.t, "integer constant %v is out of range", r.t.value)
+		}
+		for _, t := range []*integerType{integer, typeLongInt} {
+			if t.bounds().contains(value) {
+				return t, nil
+			}
+		}
+		return typeInt64, nil
+	case tokenTypeRealConst:
+		// A literal with more digits than a real holds is a DECIMAL where
+		// it is assigned to a fixed-point type. Elsewhere, literals too
+		// large for a real are invalid.
+		if r.exact {
+			return typeDecimal, nil
+		}
+		if d, ok := r.t.value.(decimal); ok && !isFinite(d.float()) {
+			return nil, newErrSemantic(r.t, "real constant %v is out of range", d)
+		}
+		return typeReal, nil
+	case tokenTypeNil:
+		return typeNil, nil
+	case tokenTypeStringConst:
+		// A string of one character is a character constant.
+		if len(r.t.value.(string)) == 1 {
+			return typeChar, nil
+		}
+		return typeString, nil
+	default:
+		return typeBoolean, nil
+	}
+}
+
+func (s *semanticAnalyzer) VisitNoOpNode(n node) (interface{}, error) {
+	return nil, nil
+}
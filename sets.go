@@ -0,0 +1,58 @@
+package go_pascal
+
+// maxSetOrdinal is the largest ordinal number a set can hold. As in Turbo
+// Pascal, sets hold at most 256 elements with ordinal numbers 0..255.
+const maxSetOrdinal = 255
+
+// set is the value of a set type. It is a bitset of the ordinal numbers of
+// its elements.
+type set [(maxSetOrdinal + 1) / 64]uint64
+
+func (s *set) include(ord int) {
+	s[ord/64] |= 1 << uint(ord%64)
+}
+
+func (s set) contains(ord int) bool {
+	return ord >= 0 && ord <= maxSetOrdinal && s[ord/64]&(1<<uint(ord%64)) != 0
+}
+
+func (s set) union(other set) set {
+	for index := range s {
+		s[index] |= other[index]
+	}
+	return s
+}
+
+func (s set) intersection(other set) set {
+	for index := range s {
+		s[index] &= other[index]
+	}
+	return s
+}
+
+func (s set) difference(other set) set {
+	for index := range s {
+		s[index] &^= other[index]
+	}
+	return s
+}
+
+// isSubset returns true if every element of s is an element of other.
+func (s set) isSubset(other set) bool {
+	return s.difference(other) == set{}
+}
+
+// compareSets evaluates the relational operators on sets: equality and the
+// subset tests <= and >=.
+func compareSets(t tokenType, left, right set) bool {
+	switch t {
+	case tokenTypeEqual:
+		return left == right
+	case tokenTypeNotEqual:
+		return left != right
+	case tokenTypeLessEqual:
+		return left.isSubset(right)
+	default:
+		return right.isSubset(left)
+	}
+}
@@ -0,0 +1,318 @@
+package go_pascal
+
+import (
+	"math"
+	"math/big"
+	"math/cmplx"
+)
+
+// realFunction is a standard function of one real argument. domain returns
+// false for the arguments the function is not defined for; it is nil for
+// functions defined for all arguments. c is the function for complex
+// arguments, if Extended Pascal defines one, and complexDomain its domain.
+type realFunction struct {
+	f             func(float64) float64
+	domain        func(float64) bool
+	c             func(complex128) complex128
+	complexDomain func(complex128) bool
+}
+
+var realFunctions = map[string]realFunction{
+	"sqrt":   {f: math.Sqrt, domain: func(x float64) bool { return x >= 0 }, c: cmplx.Sqrt},
+	"ln":     {f: math.Log, domain: func(x float64) bool { return x > 0 }, c: cmplx.Log, complexDomain: func(z complex128) bool { return z != 0 }},
+	"exp":    {f: math.Exp, c: cmplx.Exp},
+	"sin":    {f: math.Sin, c: cmplx.Sin},
+	"cos":    {f: math.Cos, c: cmplx.Cos},
+	"tan":    {f: math.Tan},
+	"arctan": {f: math.Atan, c: cmplx.Atan},
+}
+
+// checkNumericArgs checks that all arguments of a call are numbers.
+func checkNumericArgs(call *builtinCall, count int) error {
+	if err := checkArgCount(call, count); err != nil {
+		return err
+	}
+	for index := range call.args {
+		if err := checkArg(call, index, isNumeric(call.argTypes[index]), "a number"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkRealFunction(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if len(call.args) == 1 && isComplex(call.argTypes[0]) && realFunctions[call.name.value.(string)].c != nil {
+		return typeComplex, nil
+	}
+	if err := checkNumericArgs(call, 1); err != nil {
+		return nil, err
+	}
+	return typeReal, nil
+}
+
+// runRealFunction runs Sqrt, Ln, Exp, Sin, Cos, Tan or ArcTan. Arguments
+// outside the domain of the function are invalid operations, and results
+// too large for a REAL are overflows. If these exceptions are masked, the
+// result is NaN or an infinity.
+func runRealFunction(i *interpreter, call *builtinCall) (interface{}, error) {
+	value, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	function := realFunctions[call.name.value.(string)]
+	if z, ok := value.(complex128); ok {
+		return runComplexFunction(i, call, function, z)
+	}
+	x := toFloat(value)
+	// Infinities and NaN can only come from masked exceptions, and are
+	// passed on as IEEE 754 defines.
+	if !isFinite(x) {
+		return function.f(x), nil
+	}
+	if function.domain != nil && !function.domain(x) && i.options.fpuMask&fpuInvalid == 0 {
+		return nil, newErrRuntime(errCodeInvalidFloat, "invalid argument for %v: %v", call.name.value, x)
+	}
+	return checkFloat(i.options.fpuMask, function.f(x))
+}
+
+// runComplexFunction runs Sqrt, Ln, Exp, Sin, Cos or ArcTan on a complex
+// number. Exceptions are raised as for reals.
+func runComplexFunction(i *interpreter, call *builtinCall, function realFunction, z complex128) (interface{}, error) {
+	if !isFiniteComplex(z) {
+		return function.c(z), nil
+	}
+	if function.complexDomain != nil && !function.complexDomain(z) && i.options.fpuMask&fpuInvalid == 0 {
+		return nil, newErrRuntime(errCodeInvalidFloat, "invalid argument for %v: %v", call.name.value, z)
+	}
+	return checkComplex(i.options.fpuMask, function.c(z))
+}
+
+func checkAbsSqr(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if len(call.args) == 1 && isComplex(call.argTypes[0]) {
+		if call.name.value == "abs" {
+			return typeReal, nil
+		}
+		return typeComplex, nil
+	}
+	if err := checkNumericArgs(call, 1); err != nil {
+		return nil, err
+	}
+	return baseType(call.argTypes[0]), nil
+}
+
+// runAbsSqr returns the absolute value or the square of a number, which
+// keeps the type of the argument. Integer results wrap around to it. The
+// absolute value of a complex number is its magnitude, a real.
+func runAbsSqr(i *interpreter, call *builtinCall) (interface{}, error) {
+	value, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	if z, ok := value.(complex128); ok {
+		if call.name.value == "sqr" {
+			return checkComplex(i.options.fpuMask, z*z)
+		}
+		return checkFloat(i.options.fpuMask, cmplx.Abs(z))
+	}
+	typ := baseType(call.argTypes[0])
+	if x, ok := value.(float64); ok {
+		if call.name.value == "sqr" {
+			return checkFloat(i.options.fpuMask, typ.(*realType).round(x*x))
+		}
+		return math.Abs(x), nil
+	}
+	result := value
+	if call.name.value == "sqr" {
+		result = mul(value, value)
+	} else if sign(value) < 0 {
+		result = minus(0, value)
+	}
+	if typ, ok := typ.(*decimalType); ok {
+		return i.convertDecimal(typ, result, call.name)
+	}
+	return convertValue(typ, result), nil
+}
+
+func checkToInteger(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkNumericArgs(call, 1); err != nil {
+		return nil, err
+	}
+	return typeInt64, nil
+}
+
+// runToInteger runs Trunc, Round, Floor or Ceil. Round rounds halves away
+// from zero, as in Turbo Pascal. Results beyond the range of INT64 are
+// runtime errors.
+func runToInteger(i *interpreter, call *builtinCall) (interface{}, error) {
+	value, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	if isIntegerValue(value) {
+		return value, nil
+	}
+	if isDecimalValue(value) {
+		return decimalToInteger(call.name.value, toDecimal(value))
+	}
+	x := value.(float64)
+	switch call.name.value {
+	case "trunc":
+		x = math.Trunc(x)
+	case "round":
+		x = math.Round(x)
+	case "floor":
+		x = math.Floor(x)
+	default:
+		x = math.Ceil(x)
+	}
+	// float64(math.MaxInt64) rounds up to 2^63, which is out of range.
+	if math.IsNaN(x) || x < math.MinInt64 || x >= math.MaxInt64 {
+		return nil, newErrRuntime(errCodeInvalidFloat, "%v is out of the range of %v", value, typeInt64)
+	}
+	return int(x), nil
+}
+
+// decimalToInteger runs Trunc, Round, Floor or Ceil on a fixed-point number,
+// which is exact.
+func decimalToInteger(name interface{}, d decimal) (interface{}, error) {
+	var n *big.Int
+	unit := pow10(d.scale)
+	switch name {
+	case "trunc":
+		n = new(big.Int).Quo(d.unscaled, unit)
+	case "round":
+		n = roundQuo(d.unscaled, unit, false)
+	case "floor":
+		n = new(big.Int).Div(d.unscaled, unit)
+	default:
+		n = new(big.Int).Neg(new(big.Int).Div(new(big.Int).Neg(d.unscaled), unit))
+	}
+	if !n.IsInt64() {
+		return nil, newErrRuntime(errCodeInvalidFloat, "%v is out of the range of %v", d, typeInt64)
+	}
+	return int(n.Int64()), nil
+}
+
+func checkOdd(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkArgCount(call, 1); err != nil {
+		return nil, err
+	}
+	if err := checkArg(call, 0, isInteger(call.argTypes[0]), "an integer"); err != nil {
+		return nil, err
+	}
+	return typeBoolean, nil
+}
+
+func runOdd(i *interpreter, call *builtinCall) (interface{}, error) {
+	value, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	return bigInt(value).Bit(0) != 0, nil
+}
+
+func checkCmplxPolar(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkNumericArgs(call, 2); err != nil {
+		return nil, err
+	}
+	return typeComplex, nil
+}
+
+// runCmplxPolar makes a complex number from its real and imaginary parts,
+// or from its magnitude and its argument in radians.
+func runCmplxPolar(i *interpreter, call *builtinCall) (interface{}, error) {
+	args, err := evalArgs(i, call)
+	if err != nil {
+		return nil, err
+	}
+	x, y := toFloat(args[0]), toFloat(args[1])
+	if call.name.value == "polar" {
+		return cmplx.Rect(x, y), nil
+	}
+	return complex(x, y), nil
+}
+
+func checkComplexPart(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkArgCount(call, 1); err != nil {
+		return nil, err
+	}
+	typ := call.argTypes[0]
+	if err := checkArg(call, 0, isComplex(typ) || isNumeric(typ), "a complex number"); err != nil {
+		return nil, err
+	}
+	return typeReal, nil
+}
+
+// runComplexPart returns the real part, the imaginary part or the argument
+// of a complex number. Arg returns radians in -pi..pi.
+func runComplexPart(i *interpreter, call *builtinCall) (interface{}, error) {
+	value, err := i.visit(call.args[0])
+	if err != nil {
+		return nil, err
+	}
+	z := toComplex(value)
+	switch call.name.value {
+	case "re":
+		return real(z), nil
+	case "im":
+		return imag(z), nil
+	}
+	return cmplx.Phase(z), nil
+}
+
+func checkPower(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkNumericArgs(call, 2); err != nil {
+		return nil, err
+	}
+	return typeReal, nil
+}
+
+// runPower raises a base to a real exponent. A negative base needs an
+// integral exponent.
+func runPower(i *interpreter, call *builtinCall) (interface{}, error) {
+	args, err := evalArgs(i, call)
+	if err != nil {
+		return nil, err
+	}
+	base, exponent := toFloat(args[0]), toFloat(args[1])
+	if base == 0 && exponent < 0 || base < 0 && exponent != math.Trunc(exponent) {
+		if i.options.fpuMask&fpuInvalid == 0 {
+			return nil, newErrRuntime(errCodeInvalidFloat, "invalid arguments for power: %v, %v", base, exponent)
+		}
+	}
+	return checkFloat(i.options.fpuMask, math.Pow(base, exponent))
+}
+
+func checkMinMax(s *semanticAnalyzer, call *builtinCall) (dataType, error) {
+	if err := checkNumericArgs(call, 2); err != nil {
+		return nil, err
+	}
+	if isInteger(call.argTypes[0]) && isInteger(call.argTypes[1]) {
+		a, b := baseType(call.argTypes[0]).(*integerType), baseType(call.argTypes[1]).(*integerType)
+		return commonInteger([]*integerType{a, b}, a, b), nil
+	}
+	if t := decimalResult(call.argTypes[0], call.argTypes[1]); t != nil {
+		return t, nil
+	}
+	return typeReal, nil
+}
+
+// runMinMax returns the smaller or the larger of two numbers. The result is
+// an integer if both numbers are.
+func runMinMax(i *interpreter, call *builtinCall) (interface{}, error) {
+	args, err := evalArgs(i, call)
+	if err != nil {
+		return nil, err
+	}
+	result := args[1]
+	if (compare(args[0], args[1]) < 0) == (call.name.value == "min") {
+		result = args[0]
+	}
+	if isInteger(call.argTypes[0]) && isInteger(call.argTypes[1]) {
+		return result, nil
+	}
+	if t := decimalResult(call.argTypes[0], call.argTypes[1]); t != nil {
+		return convertValue(t, result), nil
+	}
+	return toFloat(result), nil
+}
@@ -0,0 +1,169 @@
+package go_pascal
+
+type symbol interface {
+	symbolName() string
+}
+
+type varSymbol struct {
+	name string
+	typ  dataType
+	// level is the nesting level of the scope the variable is declared in.
+	level int
+	// isParam is true for formal parameters, and byRef for VAR parameters
+	// among them.
+	isParam, byRef bool
+}
+
+func newVarSymbol(name string, typ dataType, level int) *varSymbol {
+	return &varSymbol{
+		name:  name,
+		typ:   typ,
+		level: level,
+	}
+}
+
+func newParamSymbol(name string, typ dataType, level int, byRef bool) *varSymbol {
+	return &varSymbol{
+		name:    name,
+		typ:     typ,
+		level:   level,
+		isParam: true,
+		byRef:   byRef,
+	}
+}
+
+func (s *varSymbol) symbolName() string {
+	return s.name
+}
+
+// fieldSymbol is a field of a record. index is the position of the field in
+// record values.
+type fieldSymbol struct {
+	name  string
+	typ   dataType
+	index int
+}
+
+func newFieldSymbol(name string, typ dataType, index int) *fieldSymbol {
+	return &fieldSymbol{
+		name:  name,
+		typ:   typ,
+		index: index,
+	}
+}
+
+func (s *fieldSymbol) symbolName() string {
+	return s.name
+}
+
+type typeSymbol struct {
+	name string
+	typ  dataType
+}
+
+func newTypeSymbol(name string, typ dataType) *typeSymbol {
+	return &typeSymbol{
+		name: name,
+		typ:  typ,
+	}
+}
+
+func (s *typeSymbol) symbolName() string {
+	return s.name
+}
+
+type constSymbol struct {
+	name  string
+	typ   dataType
+	value interface{}
+}
+
+func newConstSymbol(name string, typ dataType, value interface{}) *constSymbol {
+	return &constSymbol{
+		name:  name,
+		typ:   typ,
+		value: value,
+	}
+}
+
+func (s *constSymbol) symbolName() string {
+	return s.name
+}
+
+// routineSymbol is a procedure or a function.
+type routineSymbol struct {
+	name   string
+	params []*varSymbol
+	// returnType is nil for procedures.
+	returnType dataType
+	// result holds the return value of a function.
+	result *varSymbol
+	// level is the nesting level of the scope the routine is declared in.
+	// Its parameters and local variables live one level deeper.
+	level int
+	// block is nil as long as only a FORWARD declaration has been seen.
+	block node
+}
+
+func newRoutineSymbol(name string, level int) *routineSymbol {
+	return &routineSymbol{
+		name:  name,
+		level: level,
+	}
+}
+
+func (s *routineSymbol) symbolName() string {
+	return s.name
+}
+
+func (s *routineSymbol) isFunction() bool {
+	return s.returnType != nil
+}
+
+// procedureType returns the type of variables the routine can be assigned
+// to.
+func (s *routineSymbol) procedureType() *procedureType {
+	return newProcedureType(s.params, s.returnType)
+}
+
+// scopedSymbolTable holds the symbols declared in one scope. Lookups fall
+// back to the enclosing scopes.
+type scopedSymbolTable struct {
+	name      string
+	level     int
+	symbols   map[string]symbol
+	enclosing *scopedSymbolTable
+}
+
+func newScopedSymbolTable(name string, level int, enclosing *scopedSymbolTable) *scopedSymbolTable {
+	return &scopedSymbolTable{
+		name:      name,
+		level:     level,
+		symbols:   make(map[string]symbol),
+		enclosing: enclosing,
+	}
+}
+
+func (s *scopedSymbolTable) insert(sym symbol) {
+	s.symbols[sym.symbolName()] = sym
+}
+
+// insertAs inserts a symbol under a name other than its own, e.g. the Result
+// alias of a function's return value.
+func (s *scopedSymbolTable) insertAs(name string, sym symbol) {
+	s.symbols[name] = sym
+}
+
+// lookup finds the symbol with the given name. If currentScopeOnly is false,
+// the enclosing scopes are searched as well.
+func (s *scopedSymbolTable) lookup(name string, currentScopeOnly bool) symbol {
+	for scope := s; scope != nil; scope = scope.enclosing {
+		if sym, ok := scope.symbols[name]; ok {
+			return sym
+		}
+		if currentScopeOnly {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package go_pascal
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// defaultRealWidth is the field width of real values written without one. It
+// leaves room for ten decimals in scientific notation, as in Turbo Pascal.
+const defaultRealWidth = 17
+
+// formatValue formats a value of type t as Write does. The value is right
+// aligned in a field of the given width. decimals is negative if the number
+// of decimals was not given, in which case reals are written in scientific
+// notation and fixed-point numbers with all of their decimals. Fixed-point
+// numbers are rounded to the given decimals exactly, halves away from zero.
+func formatValue(t dataType, v interface{}, width, decimals int) string {
+	var s string
+	switch v := v.(type) {
+	case float64:
+		if !isFinite(v) {
+			s = formatNonFinite(v)
+			if width <= 0 && decimals < 0 {
+				width = defaultRealWidth
+			}
+		} else if decimals < 0 {
+			s = formatScientific(v, width)
+		} else {
+			// Halves are rounded away from zero, as in Turbo Pascal, so
+			// 2.5:0:0 is 3.
+			s = toDecimal(v).rescale(decimals, false).String()
+		}
+	case bool:
+		s = "FALSE"
+		if v {
+			s = "TRUE"
+		}
+	case int:
+		if _, ok := baseType(t).(*enumType); ok {
+			s = ordinalString(t, v)
+		} else {
+			s = strconv.Itoa(v)
+		}
+	case *big.Int:
+		s = v.String()
+	case currency, decimal:
+		if decimals < 0 {
+			s = toDecimal(v).String()
+		} else {
+			s = toDecimal(v).rescale(decimals, false).String()
+		}
+	default:
+		s = textValue(v)
+	}
+	if len(s) < width {
+		s = strings.Repeat(" ", width-len(s)) + s
+	}
+	return s
+}
+
+// formatScientific formats a real as d.dddE+dd, with as many decimals as fit
+// into width. Non-negative numbers start with a space in place of the sign.
+func formatScientific(v float64, width int) string {
+	if width <= 0 {
+		width = defaultRealWidth
+	}
+	// The sign, the first digit, the point and the exponent take 7 places.
+	decimals := width - 7
+	if decimals < 1 {
+		decimals = 1
+	}
+	s := strconv.FormatFloat(v, 'E', decimals, 64)
+	if v >= 0 {
+		s = " " + s
+	}
+	return s
+}
+
+// formatNonFinite formats infinities and NaN as Free Pascal does.
+func formatNonFinite(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "Nan"
+	case v > 0:
+		return "+Inf"
+	default:
+		return "-Inf"
+	}
+}
+
+// readError turns an error of the underlying reader into a runtime error.
+func readError(err error) error {
+	if _, ok := err.(*errRuntime); ok {
+		return err
+	}
+	return newErrRuntime(errCodeDiskRead, "%v", err)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// readWord skips white space, including line breaks, and reads the
+// characters up to the next white space. It returns an empty string at the
+// end of the input.
+func readWord(r *bufio.Reader) (string, error) {
+	var word []byte
+	for {
+		c, err := r.ReadByte()
+		if err == io.EOF {
+			return string(word), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if isSpace(c) {
+			if len(word) > 0 {
+				return string(word), r.UnreadByte()
+			}
+			continue
+		}
+		word = append(word, c)
+	}
+}
+
+// readInteger reads an integer as Read does. At the end of the input it
+// reads 0.
+func readInteger(r *bufio.Reader, unbounded bool) (interface{}, error) {
+	word, err := readWord(r)
+	if err != nil || word == "" {
+		return 0, err
+	}
+	v, err := strconv.Atoi(word)
+	if err != nil {
+		// Integers too large for an int are big integers, which only
+		// integer types without bounds can hold.
+		if b, ok := new(big.Int).SetString(word, 10); ok && unbounded {
+			return normalizeInt(b), nil
+		}
+		return 0, newErrRuntime(errCodeInvalidNumeric, "invalid numeric format: %q", word)
+	}
+	return v, nil
+}
+
+// readReal reads a real as Read does. At the end of the input it reads 0.
+func readReal(r *bufio.Reader) (float64, error) {
+	word, err := readWord(r)
+	if err != nil || word == "" {
+		return 0, err
+	}
+	v, err := strconv.ParseFloat(word, 64)
+	if err != nil {
+		return 0, newErrRuntime(errCodeInvalidNumeric, "invalid numeric format: %q", word)
+	}
+	return v, nil
+}
+
+// readDecimal reads a fixed-point number such as -12.50 exactly. At the
+// end of the input it reads 0.
+func readDecimal(r *bufio.Reader) (interface{}, error) {
+	word, err := readWord(r)
+	if err != nil || word == "" {
+		return 0, err
+	}
+	d, ok := parseDecimal(word)
+	if !ok {
+		return 0, newErrRuntime(errCodeInvalidNumeric, "invalid numeric format: %q", word)
+	}
+	return d, nil
+}
+
+// readChar reads one character. Line breaks are read as they are, and the
+// end of the input as #26, as in Turbo Pascal.
+func readChar(r *bufio.Reader) (byte, error) {
+	c, err := r.ReadByte()
+	if err == io.EOF {
+		return 26, nil
+	}
+	return c, err
+}
+
+// readString reads the rest of the current line, without the line break.
+func readString(r *bufio.Reader) (string, error) {
+	var s []byte
+	for {
+		c, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if c == '\n' {
+			if err = r.UnreadByte(); err != nil {
+				return "", err
+			}
+			break
+		}
+		s = append(s, c)
+	}
+	return strings.TrimSuffix(string(s), "\r"), nil
+}
+
+// skipLine skips the rest of the current line, including the line break.
+func skipLine(r *bufio.Reader) error {
+	_, err := r.ReadString('\n')
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
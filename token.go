@@ -5,84 +5,227 @@ import "fmt"
 type tokenType int
 
 const (
-	tokenTypeBegin tokenType = iota
+	tokenTypeArray tokenType = iota
+	tokenTypeBegin
+	tokenTypeBoolean
+	tokenTypeCase
+	tokenTypeChar
+	tokenTypeConst
+	tokenTypeDo
+	tokenTypeDownto
+	tokenTypeElse
 	tokenTypeEnd
+	tokenTypeFile
+	tokenTypeFor
+	tokenTypeFunction
+	tokenTypeIf
 	tokenTypeInteger
+	tokenTypeNil
+	tokenTypeOf
+	tokenTypeProcedure
 	tokenTypeProgram
 	tokenTypeReal
+	tokenTypeRecord
+	tokenTypeRepeat
+	tokenTypeSet
+	tokenTypeString
+	tokenTypeThen
+	tokenTypeTo
+	tokenTypeType
+	tokenTypeUntil
 	tokenTypeVar
+	tokenTypeWhile
+	tokenTypeWith
 
 	tokenTypeID
 
+	tokenTypeBooleanConst
 	tokenTypeIntegerConst
 	tokenTypeRealConst
+	tokenTypeStringConst
 
 	tokenTypeAssign
+	tokenTypeAt
+	tokenTypeCaret
 	tokenTypeColon
 	tokenTypeComma
 	tokenTypeDot
+	tokenTypeLBracket
+	tokenTypeRange
+	tokenTypeRBracket
 	tokenTypeSemi
 
+	tokenTypeEqual
+	tokenTypeGreater
+	tokenTypeGreaterEqual
+	tokenTypeIn
+	tokenTypeLess
+	tokenTypeLessEqual
+	tokenTypeNotEqual
+
+	tokenTypeAnd
+	tokenTypeNot
+	tokenTypeOr
+	tokenTypeXor
+
 	tokenTypeDivInteger
 	tokenTypeDivReal
 	tokenTypeLParen
 	tokenTypeMinus
+	tokenTypeMod
 	tokenTypeMul
 	tokenTypePlus
 	tokenTypeRParen
+	tokenTypeShl
+	tokenTypeShr
 
 	tokenTypeEOF
 	tokenTypeUnknown
 )
 
 var tokenTypeToString = map[tokenType]string{
-	tokenTypeBegin:   "keyword BEGIN",
-	tokenTypeEnd:     "keyword END",
-	tokenTypeInteger: "keyword INTEGER",
-	tokenTypeProgram: "keyword PROGRAM",
-	tokenTypeReal:    "keyword REAL",
-	tokenTypeVar:     "keyword VAR",
+	tokenTypeArray:     "keyword ARRAY",
+	tokenTypeBegin:     "keyword BEGIN",
+	tokenTypeBoolean:   "keyword BOOLEAN",
+	tokenTypeCase:      "keyword CASE",
+	tokenTypeChar:      "keyword CHAR",
+	tokenTypeConst:     "keyword CONST",
+	tokenTypeDo:        "keyword DO",
+	tokenTypeDownto:    "keyword DOWNTO",
+	tokenTypeElse:      "keyword ELSE",
+	tokenTypeEnd:       "keyword END",
+	tokenTypeFile:      "keyword FILE",
+	tokenTypeFor:       "keyword FOR",
+	tokenTypeFunction:  "keyword FUNCTION",
+	tokenTypeIf:        "keyword IF",
+	tokenTypeInteger:   "keyword INTEGER",
+	tokenTypeNil:       "keyword NIL",
+	tokenTypeOf:        "keyword OF",
+	tokenTypeProcedure: "keyword PROCEDURE",
+	tokenTypeProgram:   "keyword PROGRAM",
+	tokenTypeReal:      "keyword REAL",
+	tokenTypeRecord:    "keyword RECORD",
+	tokenTypeRepeat:    "keyword REPEAT",
+	tokenTypeSet:       "keyword SET",
+	tokenTypeString:    "keyword STRING",
+	tokenTypeThen:      "keyword THEN",
+	tokenTypeTo:        "keyword TO",
+	tokenTypeType:      "keyword TYPE",
+	tokenTypeUntil:     "keyword UNTIL",
+	tokenTypeVar:       "keyword VAR",
+	tokenTypeWhile:     "keyword WHILE",
+	tokenTypeWith:      "keyword WITH",
 
 	tokenTypeID: "identifier",
 
+	tokenTypeBooleanConst: "boolean constant",
 	tokenTypeIntegerConst: "integer number constant",
 	tokenTypeRealConst:    "real number constant",
-
-	tokenTypeAssign: "assign",
-	tokenTypeColon:  "colon",
-	tokenTypeComma:  "comma",
-	tokenTypeDot:    "dot",
-	tokenTypeSemi:   "semicolon",
+	tokenTypeStringConst:  "string constant",
+
+	tokenTypeAssign:   "assign",
+	tokenTypeAt:       "at",
+	tokenTypeCaret:    "caret",
+	tokenTypeColon:    "colon",
+	tokenTypeComma:    "comma",
+	tokenTypeDot:      "dot",
+	tokenTypeLBracket: "left bracket",
+	tokenTypeRange:    "range",
+	tokenTypeRBracket: "right bracket",
+	tokenTypeSemi:     "semicolon",
+
+	tokenTypeEqual:        "equal",
+	tokenTypeGreater:      "greater than",
+	tokenTypeGreaterEqual: "greater than or equal",
+	tokenTypeIn:           "set membership",
+	tokenTypeLess:         "less than",
+	tokenTypeLessEqual:    "less than or equal",
+	tokenTypeNotEqual:     "not equal",
+
+	tokenTypeAnd: "logical and",
+	tokenTypeNot: "logical not",
+	tokenTypeOr:  "logical or",
+	tokenTypeXor: "exclusive or",
 
 	tokenTypeDivInteger: "integer divide",
 	tokenTypeDivReal:    "real divide",
 	tokenTypeLParen:     "left paranthensis",
 	tokenTypeMinus:      "minus",
+	tokenTypeMod:        "modulo",
 	tokenTypeMul:        "multiply",
 	tokenTypePlus:       "plus",
 	tokenTypeRParen:     "right paranthensis",
+	tokenTypeShl:        "shift left",
+	tokenTypeShr:        "shift right",
 
 	tokenTypeEOF:     "EOF",
 	tokenTypeUnknown: "unknown character",
 }
 
 var keywordToToken = map[string]*token{
-	"begin":   newToken(tokenTypeBegin, nil),
-	"end":     newToken(tokenTypeEnd, nil),
-	"div":     newToken(tokenTypeDivInteger, nil),
-	"integer": newToken(tokenTypeInteger, nil),
-	"program": newToken(tokenTypeProgram, nil),
-	"real":    newToken(tokenTypeReal, nil),
-	"var":     newToken(tokenTypeVar, nil),
+	"and":       newToken(tokenTypeAnd, nil),
+	"array":     newToken(tokenTypeArray, nil),
+	"begin":     newToken(tokenTypeBegin, nil),
+	"boolean":   newToken(tokenTypeBoolean, nil),
+	"case":      newToken(tokenTypeCase, nil),
+	"char":      newToken(tokenTypeChar, nil),
+	"const":     newToken(tokenTypeConst, nil),
+	"else":      newToken(tokenTypeElse, nil),
+	"end":       newToken(tokenTypeEnd, nil),
+	"div":       newToken(tokenTypeDivInteger, nil),
+	"do":        newToken(tokenTypeDo, nil),
+	"downto":    newToken(tokenTypeDownto, nil),
+	"false":     newToken(tokenTypeBooleanConst, false),
+	"file":      newToken(tokenTypeFile, nil),
+	"for":       newToken(tokenTypeFor, nil),
+	"function":  newToken(tokenTypeFunction, nil),
+	"if":        newToken(tokenTypeIf, nil),
+	"in":        newToken(tokenTypeIn, nil),
+	"integer":   newToken(tokenTypeInteger, nil),
+	"nil":       newToken(tokenTypeNil, nil),
+	"mod":       newToken(tokenTypeMod, nil),
+	"not":       newToken(tokenTypeNot, nil),
+	"of":        newToken(tokenTypeOf, nil),
+	"or":        newToken(tokenTypeOr, nil),
+	"procedure": newToken(tokenTypeProcedure, nil),
+	"program":   newToken(tokenTypeProgram, nil),
+	"real":      newToken(tokenTypeReal, nil),
+	"record":    newToken(tokenTypeRecord, nil),
+	"repeat":    newToken(tokenTypeRepeat, nil),
+	"set":       newToken(tokenTypeSet, nil),
+	"shl":       newToken(tokenTypeShl, nil),
+	"shr":       newToken(tokenTypeShr, nil),
+	"string":    newToken(tokenTypeString, nil),
+	"then":      newToken(tokenTypeThen, nil),
+	"to":        newToken(tokenTypeTo, nil),
+	"true":      newToken(tokenTypeBooleanConst, true),
+	"type":      newToken(tokenTypeType, nil),
+	"until":     newToken(tokenTypeUntil, nil),
+	"var":       newToken(tokenTypeVar, nil),
+	"while":     newToken(tokenTypeWhile, nil),
+	"with":      newToken(tokenTypeWith, nil),
+	"xor":       newToken(tokenTypeXor, nil),
 }
 
 func (t tokenType) String() string {
 	return tokenTypeToString[t]
 }
 
+// position is a location in the source. Lines and columns count from 1.
+type position struct {
+	line, column int
+}
+
+func (p position) String() string {
+	return fmt.Sprintf("%d:%d", p.line, p.column)
+}
+
 type token struct {
 	tokenType tokenType
 	value     interface{}
+	// pos is where the token starts in the source. It is the zero position
+	// for tokens that were not read from the source.
+	pos position
 }
 
 func (t *token) String() string {
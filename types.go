@@ -0,0 +1,886 @@
+package go_pascal
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// dataType is the static type of a variable or an expression.
+type dataType interface {
+	String() string
+}
+
+// integerType is an integer type with a fixed number of bits, or without
+// bounds if bits is 0. Integer values are represented by an int, which
+// always holds a value within the bounds of the type. Values of a type
+// without bounds that do not fit into an int are represented by a *big.Int.
+type integerType struct {
+	name     string
+	bits     uint
+	unsigned bool
+}
+
+func (t *integerType) String() string {
+	return t.name
+}
+
+func (t *integerType) unbounded() bool {
+	return t.bits == 0
+}
+
+// bounds returns the smallest and the largest value of a type with bounds.
+// A type without bounds returns the range of int.
+func (t *integerType) bounds() ordinalRange {
+	if t.unbounded() {
+		return ordinalRange{low: math.MinInt64, high: math.MaxInt64}
+	}
+	if t.unsigned {
+		return ordinalRange{low: 0, high: 1<<t.bits - 1}
+	}
+	return ordinalRange{low: -1 << (t.bits - 1), high: 1<<(t.bits-1) - 1}
+}
+
+// wrap reduces v modulo 2^bits into the bounds of the type, which is what
+// the hardware does when a result overflows.
+func (t *integerType) wrap(v int) int {
+	if t.unbounded() || t.bits >= 64 {
+		return v
+	}
+	shift := 64 - t.bits
+	if t.unsigned {
+		return int(uint64(v) << shift >> shift)
+	}
+	return v << shift >> shift
+}
+
+// covers returns true if every value of the integer type other is a value
+// of t.
+func (t *integerType) covers(other *integerType) bool {
+	if t.unbounded() || other.unbounded() {
+		return t.unbounded()
+	}
+	bounds, otherBounds := t.bounds(), other.bounds()
+	return bounds.low <= otherBounds.low && otherBounds.high <= bounds.high
+}
+
+// realType is a floating-point type. Real values are represented by a
+// float64 holding a value of the precision of the type.
+type realType struct {
+	name string
+	// single is true for SINGLE, whose values are rounded to float32.
+	single bool
+	// integral is true for COMP, which holds 64-bit integers but is treated
+	// as a real type, as in Turbo Pascal.
+	integral bool
+}
+
+func (t *realType) String() string {
+	return t.name
+}
+
+// round rounds v to the precision of the type. Values too large for SINGLE
+// become infinities, and COMP rounds halves to even as the FPU does.
+func (t *realType) round(v float64) float64 {
+	switch {
+	case t.single:
+		return float64(float32(v))
+	case t.integral:
+		return math.RoundToEven(v)
+	}
+	return v
+}
+
+// decimalType is a fixed-point type, whose arithmetic is exact in decimal.
+// CURRENCY values are represented by a currency, and DECIMAL values by a
+// decimal.
+type decimalType struct {
+	name string
+	// currency is true for CURRENCY, which has four decimals and the range
+	// of a 64-bit integer scaled by 10000.
+	currency bool
+}
+
+func (t *decimalType) String() string {
+	return t.name
+}
+
+// convert converts a number to a value of the type. CURRENCY rounds it to
+// four decimals, halves to even. ok is false if the number is out of the
+// range of the type or not finite, in which case the value is the smallest
+// CURRENCY value, or 0 for DECIMAL.
+func (t *decimalType) convert(v interface{}) (value interface{}, ok bool) {
+	x, isReal := v.(float64)
+	if isReal && !isFinite(x) {
+		if t.currency {
+			return currency(math.MinInt64), false
+		}
+		return toDecimal(0), false
+	}
+	if t.currency {
+		return toDecimal(v).toCurrency()
+	}
+	return toDecimal(v), true
+}
+
+// complexType is the complex type of Extended Pascal. Complex values are
+// represented by a complex128, so both parts are double precision.
+type complexType struct {
+	name string
+}
+
+func (t *complexType) String() string {
+	return t.name
+}
+
+type booleanType struct {
+	name string
+}
+
+func (t *booleanType) String() string {
+	return t.name
+}
+
+// charType is the type of characters, which are represented by a byte.
+type charType struct {
+	name string
+}
+
+func (t *charType) String() string {
+	return t.name
+}
+
+// maxStringSize is the maximum length of strings, as in Turbo Pascal.
+const maxStringSize = 255
+
+// stringType is a string with a maximum length, such as STRING[10]. String
+// values are represented by a Go string of bytes.
+type stringType struct {
+	name string
+	size int
+}
+
+func newStringType(size int) *stringType {
+	return &stringType{size: size}
+}
+
+func (t *stringType) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	return fmt.Sprintf("STRING[%d]", t.size)
+}
+
+// enumType is an enumeration such as (Red, Green, Blue). Its values are
+// represented by their ordinal numbers.
+type enumType struct {
+	name   string
+	values []string
+}
+
+func newEnumType(values []string) *enumType {
+	return &enumType{values: values}
+}
+
+func (t *enumType) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	return "(" + strings.Join(t.values, ", ") + ")"
+}
+
+// subrangeType is a range of values of an ordinal base type, such as 0..9.
+type subrangeType struct {
+	name   string
+	base   dataType
+	bounds ordinalRange
+}
+
+func newSubrangeType(base dataType, bounds ordinalRange) *subrangeType {
+	return &subrangeType{
+		base:   base,
+		bounds: bounds,
+	}
+}
+
+func (t *subrangeType) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	return fmt.Sprintf("%v..%v", ordinalString(t.base, t.bounds.low), ordinalString(t.base, t.bounds.high))
+}
+
+// arrayType is a static array. An array with several index types is an array
+// of arrays. Array values are represented by a []interface{} holding the
+// elements.
+type arrayType struct {
+	name    string
+	index   dataType
+	element dataType
+	bounds  ordinalRange
+}
+
+func newArrayType(index, element dataType) *arrayType {
+	bounds, _ := ordinalBounds(index)
+	return &arrayType{
+		index:   index,
+		element: element,
+		bounds:  bounds,
+	}
+}
+
+func (t *arrayType) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	return fmt.Sprintf("ARRAY[%v] OF %v", t.index, t.element)
+}
+
+// length returns the number of elements of an array.
+func (t *arrayType) length() int {
+	return t.bounds.high - t.bounds.low + 1
+}
+
+// dynamicArrayType is an array whose length is set at runtime by SetLength,
+// indexed from 0. Its values are represented by a *dynamicArray. An open
+// array is a parameter declared as ARRAY OF T, which accepts any array of
+// T; its values are the []interface{} or *dynamicArray passed.
+type dynamicArrayType struct {
+	name    string
+	element dataType
+	open    bool
+}
+
+func newDynamicArrayType(element dataType) *dynamicArrayType {
+	return &dynamicArrayType{element: element}
+}
+
+func newOpenArrayType(element dataType) *dynamicArrayType {
+	return &dynamicArrayType{element: element, open: true}
+}
+
+func (t *dynamicArrayType) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	return fmt.Sprintf("ARRAY OF %v", t.element)
+}
+
+// recordType is a record. Record values are represented by a []interface{}
+// holding the fields in declaration order. Unlike in Turbo Pascal, the
+// fields of different variants do not share storage.
+type recordType struct {
+	name   string
+	fields []*fieldSymbol
+	// symbols resolves field names.
+	symbols *scopedSymbolTable
+}
+
+func newRecordType() *recordType {
+	return &recordType{symbols: newScopedSymbolTable("record", 0, nil)}
+}
+
+func (t *recordType) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	return "RECORD"
+}
+
+func (t *recordType) addField(name string, typ dataType) {
+	field := newFieldSymbol(name, typ, len(t.fields))
+	t.fields = append(t.fields, field)
+	t.symbols.insert(field)
+}
+
+// field returns the field with the given name, or nil if there is none.
+func (t *recordType) field(name string) *fieldSymbol {
+	field, _ := t.symbols.lookup(name, true).(*fieldSymbol)
+	return field
+}
+
+// setType is a set of values of an ordinal base type. Set values are
+// represented by a set. The empty set [] has no base type and is compatible
+// with every set type.
+type setType struct {
+	name string
+	base dataType
+}
+
+func newSetType(base dataType) *setType {
+	return &setType{base: base}
+}
+
+func (t *setType) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	if t.base == nil {
+		return "[]"
+	}
+	return fmt.Sprintf("SET OF %v", t.base)
+}
+
+// pointerType is a pointer to a variable of the target type. Pointer values
+// are represented by a *heapCell.
+type pointerType struct {
+	name   string
+	target dataType
+}
+
+func newPointerType(target dataType) *pointerType {
+	return &pointerType{target: target}
+}
+
+func (t *pointerType) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	return fmt.Sprintf("^%v", t.target)
+}
+
+// procedureType is the type of variables holding a procedure or a
+// function with the given parameters. returnType is nil for procedures.
+// Values are represented by a *routineValue, which is nil for NIL.
+type procedureType struct {
+	name       string
+	params     []*varSymbol
+	returnType dataType
+}
+
+func newProcedureType(params []*varSymbol, returnType dataType) *procedureType {
+	return &procedureType{params: params, returnType: returnType}
+}
+
+func (t *procedureType) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	var b strings.Builder
+	if t.returnType == nil {
+		b.WriteString("PROCEDURE")
+	} else {
+		b.WriteString("FUNCTION")
+	}
+	for index, param := range t.params {
+		if index == 0 {
+			b.WriteString("(")
+		} else {
+			b.WriteString(", ")
+		}
+		if param.byRef {
+			b.WriteString("VAR ")
+		}
+		b.WriteString(param.typ.String())
+		if index == len(t.params)-1 {
+			b.WriteString(")")
+		}
+	}
+	if t.returnType != nil {
+		fmt.Fprintf(&b, ": %v", t.returnType)
+	}
+	return b.String()
+}
+
+var (
+	typeShortInt = &integerType{name: "SHORTINT", bits: 8}
+	typeByte     = &integerType{name: "BYTE", bits: 8, unsigned: true}
+	typeSmallInt = &integerType{name: "SMALLINT", bits: 16}
+	typeWord     = &integerType{name: "WORD", bits: 16, unsigned: true}
+	typeLongInt  = &integerType{name: "LONGINT", bits: 32}
+	typeCardinal = &integerType{name: "CARDINAL", bits: 32, unsigned: true}
+	typeInt64    = &integerType{name: "INT64", bits: 64}
+	// typeBigInteger is INTEGER with big integers turned on.
+	typeBigInteger = &integerType{name: "INTEGER"}
+	// typeReal is a double precision real. DOUBLE and EXTENDED are the
+	// same type.
+	typeReal     = &realType{name: "REAL"}
+	typeSingle   = &realType{name: "SINGLE", single: true}
+	typeComp     = &realType{name: "COMP", integral: true}
+	typeCurrency = &decimalType{name: "CURRENCY", currency: true}
+	typeDecimal  = &decimalType{name: "DECIMAL"}
+	typeComplex  = &complexType{name: "COMPLEX"}
+	typeBoolean  = &booleanType{name: "BOOLEAN"}
+	typeChar     = &charType{name: "CHAR"}
+	typeString   = &stringType{name: "STRING", size: maxStringSize}
+	// typeNil is the type of NIL, which is compatible with every pointer
+	// type.
+	typeNil = &pointerType{name: "NIL"}
+)
+
+// nameType gives an anonymous type the name it is declared with in a TYPE
+// section.
+func nameType(t dataType, name string) {
+	switch t := t.(type) {
+	case *enumType:
+		if t.name == "" {
+			t.name = name
+		}
+	case *subrangeType:
+		if t.name == "" {
+			t.name = name
+		}
+	case *arrayType:
+		if t.name == "" {
+			t.name = name
+		}
+	case *recordType:
+		if t.name == "" {
+			t.name = name
+		}
+	case *setType:
+		if t.name == "" {
+			t.name = name
+		}
+	case *pointerType:
+		if t.name == "" {
+			t.name = name
+		}
+	case *stringType:
+		if t.name == "" {
+			t.name = name
+		}
+	case *fileType:
+		if t.name == "" {
+			t.name = name
+		}
+	case *procedureType:
+		if t.name == "" {
+			t.name = name
+		}
+	case *dynamicArrayType:
+		if t.name == "" {
+			t.name = name
+		}
+	}
+}
+
+// baseType returns the type a subrange is taken from, or t itself for
+// other types.
+func baseType(t dataType) dataType {
+	if subrange, ok := t.(*subrangeType); ok {
+		return subrange.base
+	}
+	return t
+}
+
+// isOrdinal returns true if the values of t are countable, i.e. every value
+// but the first and the last one has a predecessor and a successor.
+func isOrdinal(t dataType) bool {
+	switch baseType(t).(type) {
+	case *integerType, *booleanType, *charType, *enumType:
+		return true
+	}
+	return false
+}
+
+func isInteger(t dataType) bool {
+	_, ok := baseType(t).(*integerType)
+	return ok
+}
+
+func isBoolean(t dataType) bool {
+	_, ok := baseType(t).(*booleanType)
+	return ok
+}
+
+func isChar(t dataType) bool {
+	_, ok := baseType(t).(*charType)
+	return ok
+}
+
+// isText returns true for strings and characters, which can be concatenated
+// and compared with each other.
+func isText(t dataType) bool {
+	_, ok := t.(*stringType)
+	return ok || isChar(t)
+}
+
+func isNumeric(t dataType) bool {
+	switch baseType(t).(type) {
+	case *integerType, *realType, *decimalType:
+		return true
+	}
+	return false
+}
+
+func isReal(t dataType) bool {
+	_, ok := t.(*realType)
+	return ok
+}
+
+func isDecimal(t dataType) bool {
+	_, ok := t.(*decimalType)
+	return ok
+}
+
+func isComplex(t dataType) bool {
+	_, ok := t.(*complexType)
+	return ok
+}
+
+// isArray returns true for static, dynamic and open arrays.
+func isArray(t dataType) bool {
+	switch t.(type) {
+	case *arrayType, *dynamicArrayType:
+		return true
+	}
+	return false
+}
+
+// isDynamicArray returns true for dynamic arrays, but not for open arrays.
+func isDynamicArray(t dataType) bool {
+	array, ok := t.(*dynamicArrayType)
+	return ok && !array.open
+}
+
+func isOpenArray(t dataType) bool {
+	array, ok := t.(*dynamicArrayType)
+	return ok && array.open
+}
+
+func isProcedural(t dataType) bool {
+	_, ok := t.(*procedureType)
+	return ok
+}
+
+// isAssignable returns true if a value of type source can be assigned to a
+// variable of type target. Values of a subrange and of its base type are
+// assignable to each other, subject to range checks, and so are values of
+// different integer types. Files cannot be assigned.
+func isAssignable(target, source dataType) bool {
+	if _, ok := target.(*fileType); ok {
+		return false
+	}
+	if sameOrdinalBase(target, source) {
+		return true
+	}
+	if _, ok := target.(*stringType); ok {
+		return isText(source)
+	}
+	if _, ok := target.(*setType); ok {
+		return compatibleSets(target, source)
+	}
+	if _, ok := target.(*pointerType); ok {
+		return compatiblePointers(target, source)
+	}
+	if isProcedural(target) {
+		return compatibleProcedures(target, source)
+	}
+	if target, ok := target.(*dynamicArrayType); ok {
+		return compatibleArrays(target, source)
+	}
+	if isComplex(target) {
+		return isComplex(source) || isNumeric(source)
+	}
+	return (isReal(target) || isDecimal(target)) && isNumeric(source)
+}
+
+// compatibleSets returns true if a and b are set types whose elements have
+// the same base type. The empty set is compatible with every set type.
+func compatibleSets(a, b dataType) bool {
+	setA, ok := a.(*setType)
+	if !ok {
+		return false
+	}
+	setB, ok := b.(*setType)
+	if !ok {
+		return false
+	}
+	return setA.base == nil || setB.base == nil || sameOrdinalBase(setA.base, setB.base)
+}
+
+// commonInteger returns the first of the candidates that covers both integer
+// types a and b, or INT64 if none does.
+func commonInteger(candidates []*integerType, a, b dataType) *integerType {
+	integerA, integerB := baseType(a).(*integerType), baseType(b).(*integerType)
+	for _, t := range candidates {
+		if t.covers(integerA) && t.covers(integerB) {
+			return t
+		}
+	}
+	return typeInt64
+}
+
+// sameOrdinalBase returns true if a and b are the same type or subranges of
+// the same type. All integer types count as the same type.
+func sameOrdinalBase(a, b dataType) bool {
+	return a == b || baseType(a) == baseType(b) || isInteger(a) && isInteger(b)
+}
+
+// compatiblePointers returns true if a and b are pointers to the same type,
+// or if one of them is NIL.
+func compatiblePointers(a, b dataType) bool {
+	pointerA, ok := a.(*pointerType)
+	if !ok {
+		return false
+	}
+	pointerB, ok := b.(*pointerType)
+	if !ok {
+		return false
+	}
+	return pointerA == typeNil || pointerB == typeNil || pointerA.target == pointerB.target
+}
+
+// compatibleArrays returns true if a value of type source can be assigned to
+// the dynamic or open array target. Dynamic arrays of the same element type
+// are compatible, and an open array also accepts static arrays and open
+// arrays.
+func compatibleArrays(target *dynamicArrayType, source dataType) bool {
+	switch source := source.(type) {
+	case *dynamicArrayType:
+		return (target.open || !source.open) && identicalTypes(target.element, source.element)
+	case *arrayType:
+		return target.open && identicalTypes(target.element, source.element)
+	}
+	return false
+}
+
+// arrayAndNil returns true if one of a and b is a dynamic array type and the
+// other one is the type of NIL. A dynamic array equals NIL if it is empty.
+func arrayAndNil(a, b dataType) bool {
+	return isDynamicArray(a) && b == typeNil || a == typeNil && isDynamicArray(b)
+}
+
+// identicalTypes returns true if a and b are the same type. Dynamic arrays
+// are the same type if their elements are.
+func identicalTypes(a, b dataType) bool {
+	if a == b {
+		return true
+	}
+	arrayA, okA := a.(*dynamicArrayType)
+	arrayB, okB := b.(*dynamicArrayType)
+	return okA && okB && arrayA.open == arrayB.open && identicalTypes(arrayA.element, arrayB.element)
+}
+
+// compatibleProcedures returns true if a and b are procedural types with the
+// same signature, or if one of them is procedural and the other one is NIL.
+// Signatures are the same if the parameters have the same types and are
+// passed the same way, and the result types are the same; the names of the
+// parameters do not matter.
+func compatibleProcedures(a, b dataType) bool {
+	procedureA, okA := a.(*procedureType)
+	procedureB, okB := b.(*procedureType)
+	switch {
+	case okA && okB:
+	case okA:
+		return b == typeNil
+	case okB:
+		return a == typeNil
+	default:
+		return false
+	}
+	if len(procedureA.params) != len(procedureB.params) || procedureA.returnType != procedureB.returnType {
+		return false
+	}
+	for index, param := range procedureA.params {
+		if param.typ != procedureB.params[index].typ || param.byRef != procedureB.params[index].byRef {
+			return false
+		}
+	}
+	return true
+}
+
+// ordinalBounds returns the smallest and the largest ordinal number of the
+// values of t. ok is false if t has no bounds to check.
+func ordinalBounds(t dataType) (bounds ordinalRange, ok bool) {
+	switch t := t.(type) {
+	case *subrangeType:
+		return t.bounds, true
+	case *integerType:
+		return t.bounds(), !t.unbounded()
+	case *enumType:
+		return ordinalRange{low: 0, high: len(t.values) - 1}, true
+	case *booleanType:
+		return ordinalRange{low: 0, high: 1}, true
+	case *charType:
+		return ordinalRange{low: 0, high: 255}, true
+	}
+	return ordinalRange{}, false
+}
+
+// ordinalString formats the ordinal number of a value of type t.
+func ordinalString(t dataType, ord int) string {
+	switch t := baseType(t).(type) {
+	case *enumType:
+		if ord >= 0 && ord < len(t.values) {
+			return t.values[ord]
+		}
+	case *booleanType:
+		if ord == 0 {
+			return "FALSE"
+		}
+		return "TRUE"
+	case *charType:
+		if ord >= ' ' && ord <= '~' {
+			return fmt.Sprintf("'%c'", ord)
+		}
+		return fmt.Sprintf("#%d", ord)
+	}
+	return strconv.Itoa(ord)
+}
+
+// ordinalRange is a closed range of ordinal numbers.
+type ordinalRange struct {
+	low, high int
+}
+
+func (r ordinalRange) contains(ord int) bool {
+	return r.low <= ord && ord <= r.high
+}
+
+func (r ordinalRange) overlaps(other ordinalRange) bool {
+	return r.low <= other.high && other.low <= r.high
+}
+
+// ordinalValue returns the ordinal number of a value of an ordinal type.
+// Big integers are clamped to the range of int, which puts them out of the
+// bounds of every array, set and string.
+func ordinalValue(v interface{}) int {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case byte:
+		return int(v)
+	case *big.Int:
+		if v.Sign() < 0 {
+			return math.MinInt64
+		}
+		return math.MaxInt64
+	default:
+		return v.(int)
+	}
+}
+
+// ordinalToValue is the reverse of ordinalValue for values of type t.
+func ordinalToValue(t dataType, ord int) interface{} {
+	switch baseType(t).(type) {
+	case *booleanType:
+		return ord != 0
+	case *charType:
+		return byte(ord)
+	default:
+		return ord
+	}
+}
+
+// convertValue converts v to the representation of type t, e.g. an integer
+// assigned to a REAL variable becomes a float64. Integers that are out of
+// the bounds of an integer type wrap around, reals are rounded to the
+// precision of a real type, and strings that are too long for a string type
+// are truncated, as in Turbo Pascal.
+func convertValue(t dataType, v interface{}) interface{} {
+	if t, ok := t.(*realType); ok {
+		return t.round(toFloat(v))
+	}
+	if t, ok := t.(*decimalType); ok {
+		value, _ := t.convert(v)
+		return value
+	}
+	if isComplex(t) {
+		return toComplex(v)
+	}
+	if _, ok := t.(*procedureType); ok {
+		// NIL is a nil *heapCell.
+		routine, _ := v.(*routineValue)
+		return routine
+	}
+	if isOpenArray(t) {
+		// A value parameter takes the elements of the array passed, which
+		// are then copied like a static array.
+		return arrayElements(v)
+	}
+	if t, ok := baseType(t).(*integerType); ok {
+		switch v := v.(type) {
+		case int:
+			return t.wrap(v)
+		case *big.Int:
+			if !t.unbounded() {
+				return t.wrap(lowBits(v))
+			}
+		}
+	}
+	if t, ok := t.(*stringType); ok {
+		s := textValue(v)
+		if len(s) > t.size {
+			s = s[:t.size]
+		}
+		return s
+	}
+	return v
+}
+
+// textValue returns a string or a character as a string.
+func textValue(v interface{}) string {
+	if c, ok := v.(byte); ok {
+		return string([]byte{c})
+	}
+	return v.(string)
+}
+
+// copyValue copies the value of a structured type, which is assigned by
+// value. Other values are returned as they are, so dynamic arrays are shared.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		elements := make([]interface{}, len(v))
+		for index, element := range v {
+			elements[index] = copyValue(element)
+		}
+		return elements
+	}
+	return v
+}
+
+// zeroValue returns the initial value of a variable of type t.
+func zeroValue(t dataType) interface{} {
+	switch t := t.(type) {
+	case *arrayType:
+		elements := make([]interface{}, t.length())
+		for index := range elements {
+			elements[index] = zeroValue(t.element)
+		}
+		return elements
+	case *recordType:
+		fields := make([]interface{}, len(t.fields))
+		for index, field := range t.fields {
+			fields[index] = zeroValue(field.typ)
+		}
+		return fields
+	case *setType:
+		return set{}
+	case *pointerType:
+		return (*heapCell)(nil)
+	case *procedureType:
+		return (*routineValue)(nil)
+	case *dynamicArrayType:
+		return (*dynamicArray)(nil)
+	case *subrangeType:
+		return ordinalToValue(t.base, t.bounds.low)
+	case *realType:
+		return 0.0
+	case *decimalType:
+		value, _ := t.convert(0)
+		return value
+	case *complexType:
+		return complex128(0)
+	case *booleanType:
+		return false
+	case *charType:
+		return byte(0)
+	case *stringType:
+		return ""
+	case *fileType:
+		if t.element != nil {
+			return newTypedFile(t.element)
+		}
+		return &textFile{}
+	default:
+		return 0
+	}
+}
@@ -1 +1,23 @@
 package go_pascal
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// visitor knows how to visit every ast node.
+type visitor interface {
+	visit(node) (interface{}, error)
+}
+
+// dispatch calls the Visit method of v that matches the type of n, e.g.
+// VisitIfNode for an *ifNode.
+func dispatch(v visitor, n node) (interface{}, error) {
+	nodeTypeName := reflect.TypeOf(n).Elem().Name()
+	methodName := fmt.Sprintf("Visit%c%s", nodeTypeName[0]+'A'-'a', nodeTypeName[1:])
+	returnValues := reflect.ValueOf(v).MethodByName(methodName).Call([]reflect.Value{reflect.ValueOf(n)})
+	if returnValues[1].Interface() == nil {
+		return returnValues[0].Interface(), nil
+	}
+	return returnValues[0].Interface(), returnValues[1].Interface().(error)
+}